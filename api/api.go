@@ -7,8 +7,11 @@ import (
 
 	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/cosmos/cosmos-sdk/client/grpc/cmtservice"
+	"github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
 	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
-	log "github.com/sirupsen/logrus"
+	"github.com/cosmos/cosmos-sdk/x/authz"
 )
 
 // APIs in this file is added in a "if we need it, then we add it basis"
@@ -32,14 +35,12 @@ func GetAccount(targetGRPCAddress string, bech32Address string, clientCtx client
 		},
 	)
 	if err != nil {
-		log.Error(err)
 		return
 	}
 
 	ba := authtypes.BaseAccount{}
 	err = ba.Unmarshal(accountRes.Account.Value)
 	if err != nil {
-		log.Error(err)
 		return
 	}
 
@@ -55,7 +56,6 @@ func GetChainID(targetGRPCAddress string, clientCtx client.Context) (chainID str
 		return "", err
 	}
 	defer grpcConn.Close()
-	log.Info("Getting Node Info")
 
 	serviceClient := cmtservice.NewServiceClient(grpcConn)
 	nodeInfoRes, err := serviceClient.GetNodeInfo(
@@ -63,11 +63,9 @@ func GetChainID(targetGRPCAddress string, clientCtx client.Context) (chainID str
 		&cmtservice.GetNodeInfoRequest{},
 	)
 	if err != nil {
-		log.Error(err)
 		return
 	}
 
-	log.Info("node info: ", nodeInfoRes.DefaultNodeInfo)
 	return nodeInfoRes.DefaultNodeInfo.Network, nil
 }
 
@@ -85,15 +83,87 @@ func GetLatestBlockHeight(targetGRPCAddress string, clientCtx client.Context) (h
 		&cmtservice.GetLatestBlockRequest{},
 	)
 	if err != nil {
-		log.Error(err)
 		return 0, err
 	}
 	height = lastestBlockRes.SdkBlock.Header.Height
 	if height <= 0 {
 		err = errors.New(fmt.Sprintf("get latest block height is invalid: %+v\n", height))
-		log.Error(err)
 		return 0, err
 	}
 
 	return height, nil
 }
+
+// GetGrants gets the authz grants given by granter to grantee. If msgTypeURL is not empty,
+// only the grant for that msg type is returned.
+func GetGrants(targetGRPCAddress string, granter string, grantee string, msgTypeURL string, clientCtx client.Context) (grants []*authz.Grant, err error) {
+	grpcConn, err := GetGRPCConnection(targetGRPCAddress, clientCtx)
+	if err != nil {
+		return nil, err
+	}
+	defer grpcConn.Close()
+
+	authzClient := authz.NewQueryClient(grpcConn)
+	grantsRes, err := authzClient.Grants(
+		context.Background(),
+		&authz.QueryGrantsRequest{
+			Granter:    granter,
+			Grantee:    grantee,
+			MsgTypeUrl: msgTypeURL,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return grantsRes.Grants, nil
+}
+
+// GetTx gets a committed tx by its hash
+func GetTx(targetGRPCAddress string, hash string, clientCtx client.Context) (txResponse *types.TxResponse, err error) {
+	grpcConn, err := GetGRPCConnection(targetGRPCAddress, clientCtx)
+	if err != nil {
+		return nil, err
+	}
+	defer grpcConn.Close()
+
+	txClient := txtypes.NewServiceClient(grpcConn)
+	txRes, err := txClient.GetTx(
+		context.Background(),
+		&txtypes.GetTxRequest{
+			Hash: hash,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return txRes.TxResponse, nil
+}
+
+// GetBlockTxs gets the raw txs of the block at height
+func GetBlockTxs(targetGRPCAddress string, height int64, clientCtx client.Context) (txs [][]byte, err error) {
+	grpcConn, err := GetGRPCConnection(targetGRPCAddress, clientCtx)
+	if err != nil {
+		return nil, err
+	}
+	defer grpcConn.Close()
+
+	txClient := txtypes.NewServiceClient(grpcConn)
+	blockRes, err := txClient.GetBlockWithTxs(
+		context.Background(),
+		&txtypes.GetBlockWithTxsRequest{
+			Height: height,
+			// the raw txs are always returned in full, so only decode as few txs as possible
+			Pagination: &query.PageRequest{Limit: 1},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if blockRes.Block == nil {
+		return nil, fmt.Errorf("block %d not found", height)
+	}
+
+	return blockRes.Block.Data.Txs, nil
+}
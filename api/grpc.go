@@ -1,9 +1,10 @@
 package api
 
 import (
+	"fmt"
+
 	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/cosmos/cosmos-sdk/codec"
-	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 )
 
@@ -12,18 +13,19 @@ import (
 // Example:
 // grpcConn, _ := getGRPCConnection("127.0.0.1:9090")
 // defer grpcConn.Close()
-func GetGRPCConnection(targetGRPCAddress string, clientCtx client.Context) (*grpc.ClientConn, error) {
+func GetGRPCConnection(targetGRPCAddress string, clientCtx client.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	// log.Info("Obtaining gRPC connection from: ", targetGRPCAddress)
 	// Create a connection to the gRPC server.
 	grpcConn, err := grpc.Dial(
-		targetGRPCAddress,   // your gRPC server address.
-		grpc.WithInsecure(), // The SDK doesn't support any transport security mechanism.
-		// if the request/response types contain interface instead of 'nil' you should pass the application specific codec.
-		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec.NewProtoCodec(clientCtx.InterfaceRegistry).GRPCCodec())),
+		targetGRPCAddress, // your gRPC server address.
+		append([]grpc.DialOption{
+			grpc.WithInsecure(), // The SDK doesn't support any transport security mechanism.
+			// if the request/response types contain interface instead of 'nil' you should pass the application specific codec.
+			grpc.WithDefaultCallOptions(grpc.ForceCodec(codec.NewProtoCodec(clientCtx.InterfaceRegistry).GRPCCodec())),
+		}, opts...)...,
 	)
 	if err != nil {
-		log.Error("Failed to obtain gRPC connection from: ", targetGRPCAddress)
-		return nil, err
+		return nil, fmt.Errorf("failed to obtain gRPC connection from %s: %w", targetGRPCAddress, err)
 	}
 	return grpcConn, nil
 }
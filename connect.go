@@ -2,14 +2,14 @@ package carbonwalletgo
 
 import (
 	"github.com/cosmos/cosmos-sdk/client"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
+	"math"
 	"os"
 	"path"
 	"strings"
 	"time"
 
-	log "github.com/sirupsen/logrus"
-
 	"github.com/cosmos/cosmos-sdk/codec"
 	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
 	"github.com/cosmos/cosmos-sdk/crypto"
@@ -19,6 +19,7 @@ import (
 	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
 	"github.com/cosmos/cosmos-sdk/types/bech32"
 	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/sirupsen/logrus"
 
 	"github.com/Switcheo/carbon-wallet-go/api"
 	"github.com/Switcheo/carbon-wallet-go/wallet"
@@ -30,18 +31,125 @@ type WalletConfig struct {
 	TxTimeoutHeight int64
 	// Update Block Height throttle duration
 	UpdateBlockHeightLimit time.Duration
-	// The time to wait between sending out the messages in the async message queue
-	// as a single txn.
+	// The max time that a message in the message queue waits for more messages to be
+	// sent with it as a single txn. The queue is flushed earlier if it has enough messages
+	// to fill a txn up to one of the per txn limits. Set to 0 to flush as soon as possible.
 	MsgFlushInterval time.Duration
-	// Message queue buffer length for async messages - if messages have not been flushed
-	// (through MsgFlushInterval) before the buffer is full, new msgs will block.
+	// Message queue buffer length for normal priority messages - if messages have not been flushed
+	// before the buffer is full, new msgs will block.
 	MsgQueueLength int64
+	// Message queue buffer length for critical priority messages, which are flushed before
+	// any other messages.
+	CriticalMsgQueueLength int64
+	// Message queue buffer length for bulk priority messages, which are flushed after
+	// any other messages.
+	BulkMsgQueueLength int64
+	// Max time that the oldest queued message of a lower priority waits for higher priority
+	// messages to be flushed. Set to 0 to always flush strictly by priority.
+	MsgQueueStarvationTimeout time.Duration
+	// What to do with new messages when their message queue buffer is full:
+	// block, reject with wallet.ErrQueueFull, or drop the oldest bulk message to make space.
+	MsgQueueOverflowPolicy wallet.OverflowPolicy
+	// Max number of messages sent in a single txn. Queued messages beyond any of the
+	// per txn limits are sent in the following txns. Set to 0 for no limit.
+	MaxMsgsPerTx int
+	// Max estimated gas of a single txn, set to 0 for no limit.
+	MaxGasPerTx uint64
+	// Max estimated encoded size in bytes of a single txn, set to 0 for no limit.
+	MaxTxBytes int
 	// Response channel length for sync messages - if channel is not read before the
 	// buffer is full, new responses will block.
 	ResponseChannelLength int64
 	// ConfirmTransaction channel length for sync messages - if channel is not read before the
 	// buffer is full, new responses will block.
 	ConfirmTransactionChannelLength int64
+	// Backoff between polls for a txn to be confirmed, until the confirmation timeout.
+	ConfirmTransactionBackoff wallet.BackoffPolicy
+	// Max number of txns with consecutive sequences that are broadcasted concurrently.
+	// Set to 1 to broadcast one txn at a time, waiting for each broadcast to be accepted.
+	MaxInFlightTxs int
+	// Max number of times messages in a txn rejected because of an account sequence mismatch are
+	// signed again with the resynced sequence and resent, before the messages are failed.
+	MaxSequenceRetries int
+	// CometBFT RPC address, e.g. tcp://localhost:26657, to subscribe to the wallet's txn events
+	// through its websocket, so that txns are confirmed as soon as they are committed.
+	// Leave empty to only poll for each txn to be confirmed.
+	WebsocketRPCURL string
+	// Interval to poll for new blocks, scanning each block for the wallet's txns, so that txns are
+	// confirmed in one pass per block and txns past their timeout height are failed right away.
+	// Ignored if WebsocketRPCURL is set. Set to 0 to only poll for each txn to be confirmed.
+	BlockScanInterval time.Duration
+	// If true, the messages of a txn that is still not confirmed after the confirmation timeout
+	// are signed again and resent, once the txn has passed its timeout height without being
+	// committed. Requires TxTimeoutHeight to be set. If false, the messages of a txn that expired are
+	// failed, including those of a txn restored from the Journal that may never have been broadcasted.
+	RebroadcastTimedOutTxs bool
+	// Max number of times timed out messages are resent, before the messages are failed.
+	MaxRebroadcasts int
+	// Number of blocks that must be committed after a successful txn was included before its
+	// success callbacks are run. Use wallet.WithStageCallback to also be notified when the
+	// txn is accepted into the mempool and when it is included. Set to 0 to run the success
+	// callbacks as soon as the txn is included.
+	ConfirmationDepth int64
+	// If true, a txn that fails because of one of its messages is split in halves and resent
+	// until the failing message is found, so that only that message is reported as failed.
+	BisectFailedBatches bool
+	// Write-ahead journal of the submitted messages and the txns they were sent in, e.g.
+	// wallet.OpenFileJournal. On connect, the journal is replayed to confirm the txns that were
+	// broadcasted and resubmit the messages that were not, before the wallet was last stopped.
+	// The journal is not closed by the wallet. Leave nil to keep messages in memory only.
+	Journal wallet.Journal
+	// Custom validators run on each submitted message before it is queued, e.g. to reject orders
+	// outside of the allowed markets. Messages are always checked with ValidateBasic where
+	// implemented, and must be signed by the wallet or AuthzGranter.
+	MsgValidators []wallet.MsgValidator
+	// How the messages of a txn that failed with each class of errors are retried. Classes without
+	// a policy fail right away. Sequence mismatches are retried up to MaxSequenceRetries times instead.
+	RemediationPolicies map[wallet.ErrorClass]wallet.RemediationPolicy
+	// Called with the remediation decided by RemediationPolicies for a failed txn, returning
+	// the remediation to apply instead. Leave nil to apply the policies as is.
+	RemediationHook wallet.RemediationHook
+	// Sink for the async messages that failed permanently, e.g. wallet.NewMemoryDeadLetterSink or
+	// wallet.NewFileDeadLetterSink, so that they can be inspected and requeued with
+	// Wallet.RequeueDeadLetters. Leave nil to only report failures through the callbacks.
+	DeadLetterSink wallet.DeadLetterSink
+	// How long the result of a submission with an idempotency key is kept after it is done, so that
+	// submitting again with the same key returns that result instead of sending the messages again.
+	// The results are persisted in Journal if it is set. Set to 0 to only deduplicate submissions in progress.
+	IdempotencyRetention time.Duration
+	// Max number of messages that can be submitted per second, with bursts of up to
+	// MaxMsgsBurst messages, which defaults to MaxMsgsPerSecond rounded up. Messages over the
+	// limit are rejected with wallet.ErrRateLimited. Messages submitted together that are more than
+	// MaxMsgsBurst are always rejected with wallet.ErrRateBurstExceeded. Set to 0 for no limit.
+	MaxMsgsPerSecond float64
+	MaxMsgsBurst     int
+	// Max number of txns sent per second. Txns over the limit wait until they can be sent.
+	// Set to 0 for no limit.
+	MaxTxsPerSecond float64
+	// Max amounts of each denom sent by MsgSend, MsgMultiSend and IBC MsgTransfer messages within
+	// rolling windows, e.g. a daily cap with a 24h window. Messages over a limit are rejected with
+	// a *wallet.SpendLimitError. Leave empty for no limits.
+	SpendLimits []wallet.SpendLimit
+	// File that the amounts sent are persisted to, so that SpendLimits still apply after a restart.
+	// Leave empty to only track them in memory.
+	SpendLedgerPath string
+	// Prometheus metrics to collect the wallet's metrics with, labelled by the wallet label.
+	// Several wallets can share the same metrics. Leave nil to not collect metrics.
+	Metrics *wallet.Metrics
+	// Provider of the tracer that traces messages from their submission through the signing,
+	// broadcast and confirmation of their txns, e.g. the OpenTelemetry SDK's TracerProvider.
+	// Pass the caller's context with wallet.WithContext to continue its trace. Leave nil to not trace.
+	TracerProvider trace.TracerProvider
+	// Logger that the wallet logs through, e.g. wallet.NewSlogLogger, wallet.NewLogrusLogger or
+	// wallet.NewZerologLogger. Entries are logged with the wallet label. Leave nil to log with logrus.
+	Logger wallet.Logger
+	// Rewrites each logged field, e.g. to hide sensitive values. Leave nil to log messages by
+	// their type URLs instead of their payloads with wallet.RedactMsgs.
+	LogRedactor wallet.Redactor
+	// Bech32 address of the authz granter to execute messages on behalf of. If set, every tx
+	// sent by the wallet wraps its messages in a single authz.MsgExec.
+	// Leave empty to execute messages as the wallet itself.
+	AuthzGranter string
 }
 
 func DefaultWalletConfig() *WalletConfig {
@@ -50,31 +158,46 @@ func DefaultWalletConfig() *WalletConfig {
 		UpdateBlockHeightLimit:          5 * time.Second,
 		MsgFlushInterval:                100 * time.Millisecond,
 		MsgQueueLength:                  1000,
+		CriticalMsgQueueLength:          1000,
+		BulkMsgQueueLength:              10000,
+		MsgQueueStarvationTimeout:       5 * time.Second,
+		MsgQueueOverflowPolicy:          wallet.OverflowBlock,
+		MaxMsgsPerTx:                    0,
+		MaxGasPerTx:                     0,
+		MaxTxBytes:                      1048576, // CometBFT default max_tx_bytes
+		MaxInFlightTxs:                  1,
+		MaxSequenceRetries:              3,
+		RebroadcastTimedOutTxs:          false,
+		MaxRebroadcasts:                 3,
 		ResponseChannelLength:           100,
 		ConfirmTransactionChannelLength: 100,
+		ConfirmTransactionBackoff:       wallet.DefaultBackoffPolicy(),
+		IdempotencyRetention:            24 * time.Hour,
+		RemediationPolicies:             wallet.DefaultRemediationPolicies(),
 	}
 }
 
 // ConnectCliWallet connect to a cli wallet
-func ConnectCliWallet(targetGRPCAddress string, label string, password string, mainPrefix string, config *WalletConfig, clientCtx client.Context) (wallet wallet.Wallet) {
+func ConnectCliWallet(targetGRPCAddress string, label string, password string, mainPrefix string, config *WalletConfig, clientCtx client.Context) (w wallet.Wallet) {
+	logger := newLogger(config, label)
 	for {
 		chainID, err := api.GetChainID(targetGRPCAddress, clientCtx)
 		if err != nil {
-			log.Warnln(label, ": could not get chain id, will try again in a while", err.Error())
+			logger.Warn("could not get chain id, will try again in a while", wallet.F(wallet.FieldError, err))
 			time.Sleep(time.Second * 3) // polling interval
 			continue
 		}
 
 		privKey, err := getPrivKeyFromCLI(label, password)
 		if err != nil {
-			log.Warnln(label, ": could not get private key, will try again in a while", err.Error())
+			logger.Warn("could not get private key, will try again in a while", wallet.F(wallet.FieldError, err))
 			time.Sleep(time.Second * 3) // polling interval
 			continue
 		}
 
-		wallet, err = ConnectWallet(targetGRPCAddress, privKey, label, chainID, mainPrefix, config, clientCtx)
+		w, err = ConnectWallet(targetGRPCAddress, privKey, label, chainID, mainPrefix, config, clientCtx)
 		if err != nil {
-			log.Warnln(label, ": could not connect to wallet, will try again in a while", err.Error())
+			logger.Warn("could not connect to wallet, will try again in a while", wallet.F(wallet.FieldError, err))
 			time.Sleep(time.Second * 3) // polling interval
 			continue
 		}
@@ -82,11 +205,16 @@ func ConnectCliWallet(targetGRPCAddress string, label string, password string, m
 		break
 	}
 
-	return wallet
+	return w
 }
 
 // ConnectWallet - inits wallet
 func ConnectWallet(targetGRPCAddress string, privKey cmcryptotypes.PrivKey, label string, chainID string, mainPrefix string, config *WalletConfig, clientCtx client.Context) (w wallet.Wallet, err error) {
+	if config == nil {
+		config = DefaultWalletConfig()
+	}
+	logger := newLogger(config, label)
+
 	pubKey := privKey.PubKey()
 	bech32Addr, err := bech32.ConvertAndEncode(mainPrefix, pubKey.Address())
 	if err != nil {
@@ -95,7 +223,7 @@ func ConnectWallet(targetGRPCAddress string, privKey cmcryptotypes.PrivKey, labe
 	account, err := api.GetAccount(targetGRPCAddress, bech32Addr, clientCtx)
 	if err != nil {
 		if strings.Contains(err.Error(), "connect: connection refused") {
-			log.Info("connection refused, retrying...")
+			logger.Info("connection refused, retrying...")
 			time.Sleep(100 * time.Millisecond)
 			return ConnectWallet(targetGRPCAddress, privKey, label, chainID, mainPrefix, config, clientCtx)
 		}
@@ -103,39 +231,128 @@ func ConnectWallet(targetGRPCAddress string, privKey cmcryptotypes.PrivKey, labe
 	}
 
 	if account.AccountNumber == 0 {
-		log.Info("account not yet setup, will retry in a while...")
+		logger.Info("account not yet setup, will retry in a while...")
 		time.Sleep(1000 * time.Millisecond)
 		return ConnectWallet(targetGRPCAddress, privKey, label, chainID, mainPrefix, config, clientCtx)
 	}
 
-	if config == nil {
-		config = DefaultWalletConfig()
-	}
+	msgQueue := wallet.NewMsgQueue(map[wallet.Priority]int{
+		wallet.PriorityCritical: int(config.CriticalMsgQueueLength),
+		wallet.PriorityNormal:   int(config.MsgQueueLength),
+		wallet.PriorityBulk:     int(config.BulkMsgQueueLength),
+	}, config.MsgQueueStarvationTimeout, config.MsgQueueOverflowPolicy)
 
 	w = wallet.Wallet{
+		Label:                     label,
+		Logger:                    logger,
+		TracerProvider:            config.TracerProvider,
 		AccountNumber:             account.AccountNumber,
 		AccountSequence:           account.Sequence,
 		ChainID:                   chainID,
 		PrivKey:                   privKey,
 		PubKey:                    pubKey,
 		Bech32Addr:                bech32Addr,
+		Granter:                   config.AuthzGranter,
 		MainPrefix:                mainPrefix,
 		DefaultGas:                wallet.DefaultGas,
 		TxTimeoutHeight:           config.TxTimeoutHeight,
 		UpdateBlockHeightLimiter:  rate.NewLimiter(rate.Every(config.UpdateBlockHeightLimit), 1),
 		GRPCURL:                   targetGRPCAddress,
 		MsgFlushInterval:          config.MsgFlushInterval,
-		MsgQueue:                  make(chan wallet.MsgQueueItem, config.MsgQueueLength),
+		MaxMsgsPerTx:              config.MaxMsgsPerTx,
+		MaxGasPerTx:               config.MaxGasPerTx,
+		MaxTxBytes:                config.MaxTxBytes,
+		BisectFailedBatches:       config.BisectFailedBatches,
+		MsgQueue:                  msgQueue,
+		RetryBatchQueue:           make(chan []wallet.MsgQueueItem, config.MsgQueueLength),
+		RetryBacklog:              wallet.NewRetryBacklog(),
 		ResponseChannel:           make(chan wallet.SubmitMsgResponse, config.ResponseChannelLength),
 		StopChannel:               make(chan int, 3),
 		ConfirmTransactionChannel: make(chan wallet.TxItems, config.ConfirmTransactionChannelLength),
+		ConfirmTransactionBackoff: config.ConfirmTransactionBackoff,
+		Scheduler:                 wallet.NewScheduler(),
 		ClientCtx:                 clientCtx,
+		Pipeline:                  wallet.NewBroadcastPipeline(config.MaxInFlightTxs),
+		Sequences:                 wallet.NewSequenceManager(),
+		MaxSequenceRetries:        config.MaxSequenceRetries,
+		RebroadcastTimedOutTxs:    config.RebroadcastTimedOutTxs,
+		MaxRebroadcasts:           config.MaxRebroadcasts,
+		PendingTxs:                wallet.NewPendingTxs(),
+		Lifecycle:                 wallet.NewLifecycle(),
+		Journal:                   config.Journal,
+		DeadLetters:               config.DeadLetterSink,
+		MsgValidators:             config.MsgValidators,
+		RemediationPolicies:       config.RemediationPolicies,
+		RemediationHook:           config.RemediationHook,
+		Idempotency:               wallet.NewIdempotencyKeys(config.IdempotencyRetention),
+		ConfirmationDepth:         config.ConfirmationDepth,
+		Finality:                  wallet.NewFinalityTracker(),
+	}
+	defer func() {
+		// stop the scheduler and the goroutines started so far if the wallet fails to connect
+		if err != nil {
+			w.Disconnect()
+		}
+	}()
+
+	if config.MaxMsgsPerSecond > 0 {
+		burst := config.MaxMsgsBurst
+		if burst <= 0 {
+			burst = int(math.Ceil(config.MaxMsgsPerSecond))
+		}
+		w.MsgRateLimiter = rate.NewLimiter(rate.Limit(config.MaxMsgsPerSecond), burst)
+	}
+	if config.MaxTxsPerSecond > 0 {
+		w.TxRateLimiter = rate.NewLimiter(rate.Limit(config.MaxTxsPerSecond), 1)
+	}
+	if len(config.SpendLimits) > 0 {
+		w.Spend, err = wallet.NewSpendLimiter(config.SpendLimits, config.SpendLedgerPath)
+		if err != nil {
+			return
+		}
+	}
+
+	if config.MaxInFlightTxs > 1 {
+		// share a single connection between the concurrent broadcasts instead of dialing for each
+		w.GRPCConn, err = api.GetGRPCConnection(targetGRPCAddress, clientCtx, w.GRPCDialOptions()...)
+		if err != nil {
+			return
+		}
 	}
 
 	w.UpdateBlockHeight()
 
-	go w.RunProcessMsgQueue()
-	go w.RunConfirmTransactionHash()
+	if w.IsGrantee() {
+		if err := w.CheckGrants(); err != nil {
+			logger.Warn("authz grants check failed, msgs may fail until grants are given", wallet.F(wallet.FieldError, err))
+		}
+	}
+
+	if config.WebsocketRPCURL != "" {
+		w.ConfirmationBackend = wallet.NewWebsocketConfirmation(config.WebsocketRPCURL)
+	} else if config.BlockScanInterval > 0 {
+		w.ConfirmationBackend = wallet.NewBlockScanConfirmation(config.BlockScanInterval)
+	}
+
+	// registered before any goroutine that observes the metrics is started
+	if config.Metrics != nil {
+		if err = w.RegisterMetrics(config.Metrics); err != nil {
+			return
+		}
+	}
+
+	w.Lifecycle.Go(w.RunProcessMsgQueue)
+	w.Lifecycle.Go(w.RunConfirmTransactionHash)
+
+	if err = w.ReplayJournal(); err != nil {
+		return
+	}
+
+	if w.ConfirmationBackend != nil {
+		if err = w.ConfirmationBackend.Start(&w); err != nil {
+			return
+		}
+	}
 
 	return
 }
@@ -162,6 +379,18 @@ func getPrivKeyFromCLI(name, passphrase string) (cryptotypes.PrivKey, error) {
 	return privKey, nil
 }
 
+// newLogger returns the logger of the wallet with label, redacted with the LogRedactor of config
+func newLogger(config *WalletConfig, label string) wallet.Logger {
+	logger, redact := wallet.NewLogrusLogger(logrus.StandardLogger()), wallet.Redactor(wallet.RedactMsgs)
+	if config != nil && config.Logger != nil {
+		logger = config.Logger
+	}
+	if config != nil && config.LogRedactor != nil {
+		redact = config.LogRedactor
+	}
+	return wallet.NewRedactingLogger(logger, redact).With(wallet.F(wallet.FieldWallet, label))
+}
+
 func getCodec() codec.Codec {
 	registry := codectypes.NewInterfaceRegistry()
 	cryptocodec.RegisterInterfaces(registry)
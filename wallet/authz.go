@@ -0,0 +1,142 @@
+package wallet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Switcheo/carbon-wallet-go/api"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+)
+
+// IsGrantee returns true if the wallet executes msgs on behalf of Granter
+func (w *Wallet) IsGrantee() bool {
+	return w.Granter != ""
+}
+
+// EffectiveAddress returns the address that submitted msgs are executed as,
+// i.e. Granter if the wallet is a grantee, otherwise the wallet's own address
+func (w *Wallet) EffectiveAddress() string {
+	if w.IsGrantee() {
+		return w.Granter
+	}
+	return w.Bech32Addr
+}
+
+// WrapMsgs wraps msgs into a single authz.MsgExec if the wallet is a grantee,
+// otherwise msgs are returned as is
+func (w *Wallet) WrapMsgs(msgs []sdktypes.Msg) []sdktypes.Msg {
+	if !w.IsGrantee() || len(msgs) == 0 {
+		return msgs
+	}
+	msgExec := authz.NewMsgExec(w.AccAddress(), msgs)
+	// AccAddress.String() uses the global bech32 prefix, so set the grantee explicitly
+	msgExec.Grantee = w.Bech32Addr
+	return []sdktypes.Msg{&msgExec}
+}
+
+// GetGrants gets the grants given by Granter to this wallet
+func (w *Wallet) GetGrants() ([]*authz.Grant, error) {
+	if !w.IsGrantee() {
+		return nil, fmt.Errorf("wallet is not an authz grantee")
+	}
+	return api.GetGrants(w.GRPCURL, w.Granter, w.Bech32Addr, "", w.ClientCtx)
+}
+
+// CheckGrants checks that Granter has given this wallet at least one unexpired grant,
+// and that there is an unexpired grant for each of msgTypeURLs
+func (w *Wallet) CheckGrants(msgTypeURLs ...string) error {
+	grants, err := w.GetGrants()
+	if err != nil {
+		return err
+	}
+
+	granted := make(map[string]bool)
+	for _, grant := range grants {
+		if grant.Expiration != nil && grant.Expiration.Before(time.Now()) {
+			continue
+		}
+		msgTypeURL, err := grantMsgTypeURL(grant)
+		if err != nil {
+			w.logger().Warn("unable to read grant authorization", F("authorization", grant.Authorization), F(FieldError, err))
+			continue
+		}
+		granted[msgTypeURL] = true
+	}
+
+	if len(granted) == 0 {
+		return fmt.Errorf("no unexpired grants from granter %s to grantee %s", w.Granter, w.Bech32Addr)
+	}
+	for _, msgTypeURL := range msgTypeURLs {
+		if !granted[msgTypeURL] {
+			return fmt.Errorf("no unexpired grant from granter %s to grantee %s for %s", w.Granter, w.Bech32Addr, msgTypeURL)
+		}
+	}
+	return nil
+}
+
+// GrantAuthorization grants grantee an authorization to execute msgs on behalf of this wallet.
+// The grant does not expire if expiration is nil.
+func (w *Wallet) GrantAuthorization(grantee string, authorization authz.Authorization, expiration *time.Time) (*sdktypes.TxResponse, error) {
+	grant, err := authz.NewGrant(time.Now(), authorization, expiration)
+	if err != nil {
+		return nil, err
+	}
+	msg := &authz.MsgGrant{
+		Granter: w.EffectiveAddress(),
+		Grantee: grantee,
+		Grant:   grant,
+	}
+	return w.SubmitMsg(msg)
+}
+
+// GrantMsgTypes grants grantee a generic authorization for each of msgTypeURLs
+func (w *Wallet) GrantMsgTypes(grantee string, msgTypeURLs []string, expiration *time.Time) error {
+	for _, msgTypeURL := range msgTypeURLs {
+		_, err := w.GrantAuthorization(grantee, authz.NewGenericAuthorization(msgTypeURL), expiration)
+		if err != nil {
+			return fmt.Errorf("unable to grant %s: %w", msgTypeURL, err)
+		}
+	}
+	return nil
+}
+
+// RevokeAuthorization revokes the grant given by this wallet to grantee for msgTypeURL
+func (w *Wallet) RevokeAuthorization(grantee string, msgTypeURL string) (*sdktypes.TxResponse, error) {
+	msg := &authz.MsgRevoke{
+		Granter:    w.EffectiveAddress(),
+		Grantee:    grantee,
+		MsgTypeUrl: msgTypeURL,
+	}
+	return w.SubmitMsg(msg)
+}
+
+// grantMsgTypeURL returns the msg type url that grant authorizes. Authorizations that are not
+// registered in the client's interface registry can only be read if they are generic authorizations.
+func grantMsgTypeURL(grant *authz.Grant) (string, error) {
+	authorization, err := grant.GetAuthorization()
+	if err == nil {
+		return authorization.MsgTypeURL(), nil
+	}
+	if grant.Authorization == nil || grant.Authorization.TypeUrl != sdktypes.MsgTypeURL(&authz.GenericAuthorization{}) {
+		return "", err
+	}
+	var generic authz.GenericAuthorization
+	if err := generic.Unmarshal(grant.Authorization.Value); err != nil {
+		return "", err
+	}
+	return generic.MsgTypeURL(), nil
+}
+
+// msgCount returns the number of msgs, counting the msgs wrapped in an authz.MsgExec individually
+func msgCount(msgs []sdktypes.Msg) int {
+	count := 0
+	for _, msg := range msgs {
+		if msgExec, ok := msg.(*authz.MsgExec); ok {
+			count += len(msgExec.Msgs)
+			continue
+		}
+		count++
+	}
+	return count
+}
@@ -0,0 +1,167 @@
+package wallet
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// BackoffPolicy is an exponential backoff, where retry n waits for Base * Multiplier^n, up to Max,
+// randomized by up to +/- Jitter of the interval so that retries of many txs are spread out
+type BackoffPolicy struct {
+	// Base is the interval before the first retry, defaults to 5s
+	Base time.Duration
+	// Multiplier is the factor the interval grows by on each retry, set to 1 or less for a constant interval
+	Multiplier float64
+	// Max is the max interval, set to 0 for no max
+	Max time.Duration
+	// Jitter is the fraction of the interval, between 0 and 1, to randomize it by
+	Jitter float64
+}
+
+// DefaultBackoffPolicy returns the default backoff for polling for pending txs
+func DefaultBackoffPolicy() BackoffPolicy {
+	return BackoffPolicy{
+		Base:       5 * time.Second,
+		Multiplier: 1.5,
+		Max:        30 * time.Second,
+		Jitter:     0.1,
+	}
+}
+
+// Interval returns the interval to wait before the retry after retryCount retries
+func (p BackoffPolicy) Interval(retryCount uint) time.Duration {
+	base := p.Base
+	if base <= 0 {
+		base = 5 * time.Second
+	}
+	interval := float64(base)
+	if p.Multiplier > 1 {
+		interval *= math.Pow(p.Multiplier, float64(retryCount))
+	}
+	if p.Max > 0 && interval > float64(p.Max) {
+		interval = float64(p.Max)
+	}
+	if jitter := math.Min(p.Jitter, 1); jitter > 0 {
+		interval += interval * jitter * (2*rand.Float64() - 1)
+	}
+	if interval > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(interval)
+}
+
+type scheduledTask struct {
+	at time.Time
+	fn func()
+}
+
+type taskHeap []scheduledTask
+
+func (h taskHeap) Len() int            { return len(h) }
+func (h taskHeap) Less(i, j int) bool  { return h[i].at.Before(h[j].at) }
+func (h taskHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *taskHeap) Push(x interface{}) { *h = append(*h, x.(scheduledTask)) }
+func (h *taskHeap) Pop() interface{} {
+	old := *h
+	task := old[len(old)-1]
+	old[len(old)-1] = scheduledTask{}
+	*h = old[:len(old)-1]
+	return task
+}
+
+// Scheduler runs delayed tasks from a single goroutine, ordered by a timer heap, so that
+// waiting to retry many pending txs does not take a sleeping goroutine each
+type Scheduler struct {
+	mu       sync.Mutex
+	tasks    taskHeap
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewScheduler returns a running scheduler
+func NewScheduler() *Scheduler {
+	s := &Scheduler{
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// After runs fn after delay. Tasks are run one at a time, so fn must not block.
+func (s *Scheduler) After(delay time.Duration, fn func()) {
+	s.mu.Lock()
+	heap.Push(&s.tasks, scheduledTask{at: time.Now().Add(delay), fn: fn})
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Len returns the number of tasks waiting to be run
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tasks)
+}
+
+// Stop stops the scheduler, the tasks waiting to be run are dropped
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+func (s *Scheduler) run() {
+	for {
+		due, next, waiting := s.popDue()
+		for _, fn := range due {
+			fn()
+		}
+		if len(due) > 0 {
+			continue
+		}
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if waiting {
+			timer = time.NewTimer(next)
+			timeout = timer.C
+		}
+		select {
+		case <-s.stop:
+		case <-s.wake:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+	}
+}
+
+// popDue removes and returns the tasks that are due, and the time until the next task is due if any
+func (s *Scheduler) popDue() (due []func(), next time.Duration, waiting bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for len(s.tasks) > 0 && !s.tasks[0].at.After(now) {
+		due = append(due, heap.Pop(&s.tasks).(scheduledTask).fn)
+	}
+	if len(s.tasks) == 0 {
+		return due, 0, false
+	}
+	return due, s.tasks[0].at.Sub(now), true
+}
@@ -0,0 +1,50 @@
+package wallet
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBackoffPolicyInterval(t *testing.T) {
+	cases := []struct {
+		name       string
+		policy     BackoffPolicy
+		retryCount uint
+		expected   time.Duration
+	}{
+		{name: "default base", policy: BackoffPolicy{}, retryCount: 0, expected: 5 * time.Second},
+		{name: "first retry", policy: BackoffPolicy{Base: time.Second, Multiplier: 2}, retryCount: 0, expected: time.Second},
+		{name: "second retry", policy: BackoffPolicy{Base: time.Second, Multiplier: 2}, retryCount: 1, expected: 2 * time.Second},
+		{name: "fourth retry", policy: BackoffPolicy{Base: time.Second, Multiplier: 2}, retryCount: 3, expected: 8 * time.Second},
+		{name: "fractional multiplier", policy: BackoffPolicy{Base: 2 * time.Second, Multiplier: 1.5}, retryCount: 2, expected: 4500 * time.Millisecond},
+		{name: "constant", policy: BackoffPolicy{Base: time.Second, Multiplier: 1}, retryCount: 5, expected: time.Second},
+		{name: "capped", policy: BackoffPolicy{Base: time.Second, Multiplier: 2, Max: 5 * time.Second}, retryCount: 3, expected: 5 * time.Second},
+		{name: "overflow", policy: BackoffPolicy{Base: time.Second, Multiplier: 10}, retryCount: 100, expected: time.Duration(math.MaxInt64)},
+	}
+	for _, c := range cases {
+		if interval := c.policy.Interval(c.retryCount); interval != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, interval)
+		}
+	}
+}
+
+func TestBackoffPolicyJitter(t *testing.T) {
+	policy := BackoffPolicy{Base: 10 * time.Second, Multiplier: 2, Max: 30 * time.Second, Jitter: 0.1}
+	cases := []struct {
+		retryCount uint
+		min, max   time.Duration
+	}{
+		{retryCount: 0, min: 9 * time.Second, max: 11 * time.Second},
+		{retryCount: 1, min: 18 * time.Second, max: 22 * time.Second},
+		{retryCount: 5, min: 27 * time.Second, max: 33 * time.Second},
+	}
+	for _, c := range cases {
+		for i := 0; i < 100; i++ {
+			interval := policy.Interval(c.retryCount)
+			if interval < c.min || interval > c.max {
+				t.Fatalf("retry %d: expected interval within [%s, %s], got %s", c.retryCount, c.min, c.max, interval)
+			}
+		}
+	}
+}
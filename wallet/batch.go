@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+// txBytesOverhead is the estimated size of the encoded tx without its msgs,
+// i.e. the signature, signer info, fee and remaining tx body fields
+const txBytesOverhead = 512
+
+// batch tracks the estimated size of the msgs to be sent in a single tx
+type batch struct {
+	items []MsgQueueItem
+	msgs  int
+	gas   uint64
+	bytes int
+}
+
+// nextBatch takes items from the msg queue, highest priority first, until the queue is empty or
+// the next item would exceed the wallet's batch limits. Items are never split, so an item with msgs
+// that must be sent together is always sent in a single tx, even if it exceeds the limits by itself.
+func (w *Wallet) nextBatch() []MsgQueueItem {
+	b := batch{bytes: txBytesOverhead}
+	accept := func(item MsgQueueItem) bool {
+		return len(b.items) == 0 || w.fitsBatch(b, item)
+	}
+	for {
+		item, ok := w.MsgQueue.PopIf(accept)
+		if !ok {
+			break
+		}
+		b.add(item)
+	}
+	return b.items
+}
+
+// fitsBatch returns true if item can be added to b without exceeding the wallet's batch limits.
+// A limit of 0 is treated as no limit.
+func (w *Wallet) fitsBatch(b batch, item MsgQueueItem) bool {
+	msgs, gas, bytes := itemSize(item)
+	if w.MaxMsgsPerTx > 0 && b.msgs+msgs > w.MaxMsgsPerTx {
+		return false
+	}
+	if w.MaxGasPerTx > 0 && b.gas+gas > w.MaxGasPerTx {
+		return false
+	}
+	if w.MaxTxBytes > 0 && b.bytes+bytes > w.MaxTxBytes {
+		return false
+	}
+	return true
+}
+
+// batchReady returns true if the msg queue has enough items to fill a batch up to one of the batch limits
+func (w *Wallet) batchReady() bool {
+	msgs, gas, bytes := w.MsgQueue.Size()
+	if w.MaxMsgsPerTx > 0 && msgs >= w.MaxMsgsPerTx {
+		return true
+	}
+	if w.MaxGasPerTx > 0 && gas >= w.MaxGasPerTx {
+		return true
+	}
+	if w.MaxTxBytes > 0 && txBytesOverhead+bytes >= w.MaxTxBytes {
+		return true
+	}
+	return false
+}
+
+func (b *batch) add(item MsgQueueItem) {
+	msgs, gas, bytes := itemSize(item)
+	b.items = append(b.items, item)
+	b.msgs += msgs
+	b.gas += gas
+	b.bytes += bytes
+}
+
+// itemSize returns the number of msgs, the estimated gas and the estimated encoded bytes of item
+func itemSize(item MsgQueueItem) (msgs int, gas uint64, bytes int) {
+	itemMsgs := item.GetMsgs()
+	for _, msg := range itemMsgs {
+		bytes += msgBytes(msg)
+	}
+	return len(itemMsgs), txFeeAmount(itemMsgs).Uint64(), bytes
+}
+
+// msgBytes returns the estimated size of msg when encoded in a tx
+func msgBytes(msg sdktypes.Msg) int {
+	msgAny, err := codectypes.NewAnyWithValue(msg)
+	if err != nil {
+		return 0
+	}
+	// tag and length prefix of the repeated any field
+	return msgAny.Size() + 4
+}
@@ -0,0 +1,88 @@
+package wallet
+
+import (
+	"testing"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// batchWallet returns a wallet with items queued
+func batchWallet(t *testing.T, items ...MsgQueueItem) *Wallet {
+	t.Helper()
+	w := &Wallet{MsgQueue: NewMsgQueue(nil, 0, OverflowBlock)}
+	for _, item := range items {
+		if _, err := w.MsgQueue.Push(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w
+}
+
+// batchIDs takes batches from the msg queue of w until it is empty, returning the item IDs of each
+func batchIDs(w *Wallet) [][]string {
+	batches := [][]string{}
+	for {
+		items := w.nextBatch()
+		if len(items) == 0 {
+			return batches
+		}
+		batches = append(batches, itemIDs(items))
+	}
+}
+
+func TestNextBatchMaxMsgsPerTx(t *testing.T) {
+	w := batchWallet(t,
+		priorityItem("a", PriorityNormal),
+		priorityItem("b", PriorityNormal),
+		priorityItem("c", PriorityNormal),
+	)
+	w.MaxMsgsPerTx = 2
+	if !w.batchReady() {
+		t.Fatal("expected batch to be ready once the queue has MaxMsgsPerTx msgs")
+	}
+
+	batches := batchIDs(w)
+	if len(batches) != 2 || !equalIDs(batches[0], []string{"a", "b"}) || !equalIDs(batches[1], []string{"c"}) {
+		t.Fatalf("expected batches [a b] [c], got %v", batches)
+	}
+}
+
+func TestNextBatchMaxTxBytes(t *testing.T) {
+	item := priorityItem("a", PriorityNormal)
+	_, _, bytes := itemSize(item)
+	w := batchWallet(t, item, priorityItem("b", PriorityNormal))
+	w.MaxTxBytes = txBytesOverhead + bytes
+	if !w.batchReady() {
+		t.Fatal("expected batch to be ready once the queue has MaxTxBytes of msgs")
+	}
+
+	if batches := batchIDs(w); len(batches) != 2 {
+		t.Fatalf("expected an item per batch, got %v", batches)
+	}
+}
+
+func TestNextBatchWithoutLimits(t *testing.T) {
+	w := batchWallet(t,
+		priorityItem("a", PriorityNormal),
+		priorityItem("b", PriorityNormal),
+	)
+	if w.batchReady() {
+		t.Fatal("expected batch without limits to never be ready early")
+	}
+	if batches := batchIDs(w); len(batches) != 1 || len(batches[0]) != 2 {
+		t.Fatalf("expected a single batch, got %v", batches)
+	}
+}
+
+func TestNextBatchNeverSplitsMsgGroups(t *testing.T) {
+	group := MsgQueueItem{ID: "group", Msgs: []sdktypes.Msg{&banktypes.MsgSend{}, &banktypes.MsgSend{}, &banktypes.MsgSend{}}}
+	w := batchWallet(t, priorityItem("a", PriorityNormal), group, priorityItem("b", PriorityNormal))
+	w.MaxMsgsPerTx = 2
+
+	// the group exceeds MaxMsgsPerTx by itself, so it is sent alone rather than split
+	batches := batchIDs(w)
+	if len(batches) != 3 || !equalIDs(batches[0], []string{"a"}) || !equalIDs(batches[1], []string{"group"}) || !equalIDs(batches[2], []string{"b"}) {
+		t.Fatalf("expected batches [a] [group] [b], got %v", batches)
+	}
+}
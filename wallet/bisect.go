@@ -0,0 +1,82 @@
+package wallet
+
+import (
+	"strings"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+// txLevelErrors are the sdk errors that apply to a tx as a whole,
+// rather than to any one of its msgs. Splitting the tx does not help with these.
+var txLevelErrors = []abciError{
+	sdkerrors.ErrTxDecode,
+	sdkerrors.ErrUnauthorized,
+	ErrInsufficientFunds, // for fees when there is no msg index
+	sdkerrors.ErrInvalidPubKey,
+	ErrOutOfGas,
+	ErrInsufficientFee,
+	ErrTxInMempoolCache,
+	ErrMempoolFull,
+	sdkerrors.ErrTxTooLarge,
+	ErrTimeoutHeight,
+	ErrSequenceMismatch,
+}
+
+// isMsgLevelError returns true if the failure in response was caused by one of the tx msgs
+func isMsgLevelError(response *sdktypes.TxResponse) bool {
+	if strings.Contains(response.RawLog, "message index") {
+		return true
+	}
+	for _, txLevelError := range txLevelErrors {
+		if responseIs(response, txLevelError) {
+			return false
+		}
+	}
+	return true
+}
+
+// shouldBisect returns true if items failed with response because of one of their msgs
+// and should be retried in halves to find the failing msg
+func (w *Wallet) shouldBisect(items []MsgQueueItem, response *sdktypes.TxResponse) bool {
+	return w.BisectFailedBatches && len(items) > 1 && response != nil && response.Code != 0 && isMsgLevelError(response)
+}
+
+// bisect splits items into two halves. Items with msgs that must be sent together are not split.
+func bisect(items []MsgQueueItem) (left []MsgQueueItem, right []MsgQueueItem) {
+	mid := len(items) / 2
+	return items[:mid], items[mid:]
+}
+
+// retryBisected requeues the items of a tx that failed on delivery as two separate batches
+func (w *Wallet) retryBisected(items []MsgQueueItem) {
+	w.logger().Warn("tx failed, retrying msgs in halves", F("msg_count", len(items)))
+	w.retryBatches(bisect(retriesAfterBroadcast(items)))
+}
+
+// retriesAfterBroadcast returns items to be retried in a new tx after their tx was broadcasted.
+// Sync items were responded to when their tx was broadcasted, so they are retried as async items
+// without responding again.
+func retriesAfterBroadcast(items []MsgQueueItem) []MsgQueueItem {
+	retries := make([]MsgQueueItem, len(items))
+	for i, item := range items {
+		item.Async = true
+		retries[i] = item
+	}
+	return retries
+}
+
+// resubmit sends each non-empty batch as a separate tx. With a pipeline, txs can only be signed
+// by the goroutine processing the msg queue, so the batches are requeued to it.
+// Without one, must only be called from the goroutine processing the msg queue.
+func (w *Wallet) resubmit(batches ...[]MsgQueueItem) {
+	if w.Pipeline != nil {
+		w.retryBatches(batches...)
+		return
+	}
+	for _, b := range batches {
+		if len(b) > 0 {
+			w.processBatch(b)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Switcheo/carbon-wallet-go/api"
+	cmttypes "github.com/cometbft/cometbft/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BlockScanConfirmation is a ConfirmationBackend that follows new block heights, matching the txs of
+// each block against the wallet's pending txs, so that all pending txs committed in a block are resolved
+// in one pass. Pending txs that pass their timeout height without being committed are expired right away.
+type BlockScanConfirmation struct {
+	// Interval is the time between polls for new blocks
+	Interval time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	// last scanned height, only accessed by the scanning goroutine
+	height int64
+}
+
+// NewBlockScanConfirmation returns a block scanning confirmation backend that polls for new blocks every interval
+func NewBlockScanConfirmation(interval time.Duration) *BlockScanConfirmation {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &BlockScanConfirmation{
+		Interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start scans new blocks for the pending txs of w in the background
+func (c *BlockScanConfirmation) Start(w *Wallet) error {
+	go func() {
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				c.scan(w)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops scanning
+func (c *BlockScanConfirmation) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+// scan scans the blocks committed since the last scan
+func (c *BlockScanConfirmation) scan(w *Wallet) {
+	latest, err := api.GetLatestBlockHeight(w.GRPCURL, w.ClientCtx)
+	if err != nil {
+		w.logger().Warn("block scan: unable to get latest block height", F(FieldError, err))
+		return
+	}
+	if c.height == 0 {
+		// txs broadcasted before the first scan are still found by polling
+		c.height = latest - 1
+	}
+
+	for c.height < latest {
+		select {
+		case <-c.stop:
+			return
+		default:
+		}
+		if w.PendingTxs.Len() == 0 {
+			// nothing to match, skip ahead without fetching the blocks
+			c.height = latest
+			return
+		}
+		height := c.height + 1
+		if err := c.scanBlock(w, height); err != nil {
+			// retried on the next scan
+			w.logger().Warn("block scan: unable to scan block", F("height", height), F(FieldError, err))
+			return
+		}
+		c.height = height
+	}
+}
+
+// scanBlock resolves the pending txs committed at height, and expires the pending txs
+// whose timeout height is lower than height that were not committed
+func (c *BlockScanConfirmation) scanBlock(w *Wallet, height int64) error {
+	txs, err := api.GetBlockTxs(w.GRPCURL, height, w.ClientCtx)
+	if err != nil {
+		return err
+	}
+
+	committed := make(map[string]bool, len(txs))
+	for _, tx := range txs {
+		committed[strings.ToUpper(hex.EncodeToString(cmttypes.Tx(tx).Hash()))] = true
+	}
+
+	for _, txItems := range w.PendingTxs.List() {
+		if committed[txItems.Hash] {
+			// the block only has the raw txs, so fetch the results of the matches
+			response, err := api.GetTx(w.GRPCURL, txItems.Hash, w.ClientCtx)
+			if err != nil {
+				// resolved by polling instead
+				w.logger().Warn("block scan: unable to get tx", F(FieldTxHash, txItems.Hash), F(FieldError, err))
+				continue
+			}
+			w.ResolveTx(response)
+			continue
+		}
+		if pastTimeoutHeight(height, txItems) {
+			c.expire(w, txItems)
+		}
+	}
+	return nil
+}
+
+// expire expires a pending tx that has passed its timeout height, after checking that
+// it was not committed, in case it was broadcasted before the first scan
+func (c *BlockScanConfirmation) expire(w *Wallet, txItems TxItems) {
+	response, err := api.GetTx(w.GRPCURL, txItems.Hash, w.ClientCtx)
+	if err == nil {
+		w.ResolveTx(response)
+		return
+	}
+	if status.Code(err) != codes.NotFound {
+		// unknown if it was committed, so leave it to polling
+		w.logger().Warn("block scan: unable to get tx", F(FieldTxHash, txItems.Hash), F(FieldError, err))
+		return
+	}
+	w.expireTx(txItems)
+}
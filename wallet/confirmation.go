@@ -2,36 +2,79 @@ package wallet
 
 import (
 	"context"
-	"fmt"
 	"github.com/Switcheo/carbon-wallet-go/api"
 	"github.com/cosmos/cosmos-sdk/types"
 	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
-	log "github.com/sirupsen/logrus"
-	"math"
 	"strings"
 	"time"
 )
 
 func (w *Wallet) runCallback(response *types.TxResponse, items []MsgQueueItem, err error) {
+	w.journalDone(items)
 	for _, item := range items {
-		if item.Callback != nil {
-			item.Callback(response, item.Msg, err)
-		}
+		w.completeIdempotent(item, response, err)
+		w.deadLetter(item, response, err)
+		item.RunCallback(response, err)
 	}
 }
 
 // RetryConfirmTransaction drops retry if txItems was created since timeout,
-// otherwise sends txItems to ConfirmTransactionChannel
+// otherwise sends txItems to ConfirmTransactionChannel.
+// If the items of txItems are resent once it expires, it is confirmed until it is committed or expires instead of dropped.
 func (w *Wallet) RetryConfirmTransaction(txItems TxItems) {
+	if !w.PendingTxs.Has(txItems.Hash) {
+		return
+	}
+	if txItems.Unbroadcast && txItems.TimeoutHeight > 0 {
+		// a tx that may never have been broadcasted is resent as soon as it provably expires
+		w.rebroadcastTimedOut(txItems)
+		return
+	}
 	if time.Now().After(txItems.CreatedAt.Add(w.GetConfirmTransactionTimeout())) {
+		if w.resendsExpired(txItems) {
+			w.rebroadcastTimedOut(txItems)
+			return
+		}
+		if _, ok := w.removePending(txItems.Hash); !ok {
+			return
+		}
 		response := types.TxResponse{TxHash: txItems.Hash}
-		w.runCallback(&response, txItems.Items, fmt.Errorf("transaction error: transaction timed out"))
-		log.Errorf("RetryConfirmTransaction timeout for %+v", txItems.Hash)
+		endConfirmSpan(txItems, &response, ErrTxTimedOut)
+		w.runCallback(&response, txItems.Items, ErrTxTimedOut)
+		w.logger().Error("tx confirmation timed out", F(FieldTxHash, txItems.Hash), F(FieldSequence, txItems.Sequence))
+		w.sequenceGap(txItems)
 		return
 	}
-	time.Sleep(w.GetConfirmTransactionRetryInterval(txItems))
+	w.requeueConfirmTransaction(txItems)
+}
+
+// requeueConfirmTransaction sends txItems to ConfirmTransactionChannel after the retry interval.
+// The wait is run on Scheduler if set, otherwise it blocks.
+func (w *Wallet) requeueConfirmTransaction(txItems TxItems) {
+	interval := w.GetConfirmTransactionRetryInterval(txItems)
 	txItems.RetryCount++
-	w.ConfirmTransactionChannel <- txItems
+	if w.Scheduler == nil {
+		time.Sleep(interval)
+		w.sendConfirmTransaction(txItems)
+		return
+	}
+	w.Scheduler.After(interval, func() {
+		select {
+		case w.ConfirmTransactionChannel <- txItems:
+		default:
+			// do not hold up the scheduler while the channel is full
+			go w.sendConfirmTransaction(txItems)
+		}
+	})
+}
+
+// sendConfirmTransaction sends txItems to ConfirmTransactionChannel, unless the wallet is stopped,
+// in which case the pending tx is failed on shutdown
+func (w *Wallet) sendConfirmTransaction(txItems TxItems) {
+	select {
+	case w.ConfirmTransactionChannel <- txItems:
+	case <-w.StopChannel:
+	}
 }
 
 func (w *Wallet) GetConfirmTransactionTimeout() time.Duration {
@@ -42,21 +85,52 @@ func (w *Wallet) GetConfirmTransactionTimeout() time.Duration {
 	return timeout
 }
 
-// GetConfirmTransactionRetryInterval returns exponential backoff and add ConfirmTransactionMinInterval
+// GetConfirmTransactionRetryInterval returns the interval before the next poll for txItems following
+// ConfirmTransactionBackoff. ConfirmTransactionMinInterval is used as the base interval if the policy has none,
+// and the base interval is at least the fallback interval when polling is only a fallback for ConfirmationBackend.
 func (w *Wallet) GetConfirmTransactionRetryInterval(txItems TxItems) time.Duration {
-	multiply := time.Duration(math.Pow(2, float64(txItems.RetryCount)))
-	interval := w.ConfirmTransactionMinInterval
+	policy := w.ConfirmTransactionBackoff
+	if policy.Base == 0 {
+		policy.Base = w.ConfirmTransactionMinInterval
+	}
+	if w.ConfirmationBackend != nil {
+		if fallback := w.GetConfirmTransactionFallbackInterval(); policy.Base < fallback {
+			policy.Base = fallback
+		}
+	}
+	return policy.Interval(txItems.RetryCount)
+}
+
+// GetConfirmTransactionFallbackInterval returns the min interval between polls for a pending tx
+// when a ConfirmationBackend is set, as polling is then only a fallback
+func (w *Wallet) GetConfirmTransactionFallbackInterval() time.Duration {
+	interval := w.ConfirmTransactionFallbackInterval
 	if interval == 0 {
-		interval = 5 * time.Second
+		interval = 30 * time.Second
 	}
-	return interval + multiply
+	return interval
+}
+
+// ConfirmationBackend resolves pending txs as soon as they are committed. Each pending tx is
+// still polled for as a fallback, until it is resolved by either the backend or the polling.
+type ConfirmationBackend interface {
+	// Start starts resolving the pending txs of w in the background
+	Start(w *Wallet) error
+	// Stop stops the backend
+	Stop()
 }
 
 func (w *Wallet) ConfirmTransactionHash(txItems TxItems) {
+	if !w.PendingTxs.Has(txItems.Hash) {
+		// resolved by the confirmation backend
+		return
+	}
+
 	grpcConn, err := api.GetGRPCConnection(w.GRPCURL, w.ClientCtx)
 	if err != nil {
 		go w.RetryConfirmTransaction(txItems)
-		log.Error("unable to open grpcConn")
+		w.logger().Error("unable to open grpc connection", F(FieldTxHash, txItems.Hash), F(FieldError, err))
+		return
 	}
 	defer grpcConn.Close()
 
@@ -65,18 +139,50 @@ func (w *Wallet) ConfirmTransactionHash(txItems TxItems) {
 	if err != nil {
 		go w.RetryConfirmTransaction(txItems)
 		if !strings.Contains(err.Error(), "code = NotFound") {
-			log.Errorf("ProcessTransactionHash.GetTx failed: %+v\n", err.Error())
+			w.logger().Error("unable to get tx", F(FieldTxHash, txItems.Hash), F(FieldError, err))
 		}
 		return
 	}
 
-	response := grpcRes.TxResponse
+	if _, ok := w.removeCommitted(grpcRes.TxResponse); !ok {
+		return
+	}
+	w.onTxCommitted(txItems, grpcRes.TxResponse)
+}
+
+// ResolveTx resolves the pending tx committed with response, unless it has been resolved already
+func (w *Wallet) ResolveTx(response *types.TxResponse) {
+	txItems, ok := w.removeCommitted(response)
+	if !ok {
+		return
+	}
+	w.onTxCommitted(txItems, response)
+}
+
+// onTxCommitted runs the callbacks of txItems committed with response. The success callbacks of
+// a successful tx are run once ConfirmationDepth blocks have been committed after it.
+func (w *Wallet) onTxCommitted(txItems TxItems, response *types.TxResponse) {
+	w.runStageCallback(StageIncluded, response, txItems.Items)
+	w.observeCommitted(txItems, response)
 	if response.Code == 0 {
-		log.Infof("Transaction succeeded: %+v", response.TxHash)
-		w.runCallback(response, txItems.Items, nil)
+		w.logger().Info("transaction succeeded", F(FieldTxHash, response.TxHash), F(FieldSequence, txItems.Sequence), F("height", response.Height))
+		endConfirmSpan(txItems, response, nil)
+		if w.ConfirmationDepth > 0 && w.Finality != nil {
+			w.Finality.await(w, txItems, response)
+			return
+		}
+		w.finalizeTx(txItems, response)
 	} else {
-		log.Errorf("Transaction failed: txHash: %+v, code: %+v, raw_log: %+v\n", response.TxHash, response.Code, response.RawLog)
-		w.runCallback(response, txItems.Items, fmt.Errorf("transaction error: transaction failed"))
+		w.logger().Error("transaction failed", F(FieldTxHash, response.TxHash), F(FieldSequence, txItems.Sequence), F("codespace", response.Codespace), F("code", response.Code), F("raw_log", response.RawLog))
+		endConfirmSpan(txItems, response, NewTxError(response, true))
+		if w.shouldBisect(txItems.Items, response) {
+			w.retryBisected(txItems.Items)
+			return
+		}
+		if w.remediateCommitted(txItems, response) {
+			return
+		}
+		w.runCallback(response, txItems.Items, NewTxError(response, true))
 	}
 }
 
@@ -87,6 +193,11 @@ func (w *Wallet) RunConfirmTransactionHash() {
 		case <-w.StopChannel:
 			return
 		case txItems := <-w.ConfirmTransactionChannel:
+			if w.ConfirmationBackend != nil && txItems.RetryCount == 0 {
+				// give the backend a chance to resolve the tx before polling for it
+				go w.RetryConfirmTransaction(txItems)
+				continue
+			}
 			go w.ConfirmTransactionHash(txItems)
 		}
 	}
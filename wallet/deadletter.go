@@ -0,0 +1,314 @@
+package wallet
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/google/uuid"
+)
+
+// DeadLetter is an async item that failed permanently, with the context of its failure
+type DeadLetter struct {
+	ItemID         string
+	Msgs           []sdktypes.Msg
+	Priority       Priority
+	IdempotencyKey string
+	Error          string
+	Codespace      string
+	Code           uint32
+	RawLog         string
+	TxHash         string
+	Attempts       int
+	FailedAt       time.Time
+	// Callback of the item, which is only kept by sinks in memory
+	Callback func(*sdktypes.TxResponse, sdktypes.Msg, error)
+}
+
+// DeadLetterSink receives the async items that failed permanently, so that
+// failures are never silently dropped, and the items can be requeued later
+type DeadLetterSink interface {
+	// Put adds letter to the sink
+	Put(letter DeadLetter) error
+	// List returns the letters in the sink, in the order they were added
+	List() ([]DeadLetter, error)
+	// Remove removes the letter of the item with itemID
+	Remove(itemID string) error
+}
+
+// MemoryDeadLetterSink is a DeadLetterSink that keeps letters in memory
+type MemoryDeadLetterSink struct {
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+// NewMemoryDeadLetterSink returns an empty in-memory dead letter sink
+func NewMemoryDeadLetterSink() *MemoryDeadLetterSink {
+	return &MemoryDeadLetterSink{}
+}
+
+// Put adds letter to the sink
+func (s *MemoryDeadLetterSink) Put(letter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters = append(s.letters, letter)
+	return nil
+}
+
+// List returns the letters in the sink
+func (s *MemoryDeadLetterSink) List() ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]DeadLetter{}, s.letters...), nil
+}
+
+// Remove removes the letter of the item with itemID
+func (s *MemoryDeadLetterSink) Remove(itemID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letters := s.letters[:0]
+	for _, letter := range s.letters {
+		if letter.ItemID != itemID {
+			letters = append(letters, letter)
+		}
+	}
+	for i := len(letters); i < len(s.letters); i++ {
+		s.letters[i] = DeadLetter{}
+	}
+	s.letters = letters
+	return nil
+}
+
+// fileDeadLetter is a line of a FileDeadLetterSink
+type fileDeadLetter struct {
+	ItemID         string            `json:"item_id"`
+	Removed        bool              `json:"removed,omitempty"`
+	Msgs           []json.RawMessage `json:"msgs,omitempty"`
+	Priority       Priority          `json:"priority,omitempty"`
+	IdempotencyKey string            `json:"idempotency_key,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	Codespace      string            `json:"codespace,omitempty"`
+	Code           uint32            `json:"code,omitempty"`
+	RawLog         string            `json:"raw_log,omitempty"`
+	TxHash         string            `json:"tx_hash,omitempty"`
+	Attempts       int               `json:"attempts,omitempty"`
+	FailedAt       time.Time         `json:"failed_at,omitempty"`
+}
+
+// FileDeadLetterSink is a DeadLetterSink that appends letters to a file as JSON lines.
+// Removed letters are recorded as removed, rather than deleted from the file.
+type FileDeadLetterSink struct {
+	// Logger logs the invalid letters that are skipped if set, otherwise they are logged with DefaultLogger
+	Logger Logger
+	mu     sync.Mutex
+	path   string
+	cdc    codec.JSONCodec
+}
+
+// NewFileDeadLetterSink returns a dead letter sink that appends to the file at path,
+// encoding msgs with cdc, which must have all msg types registered
+func NewFileDeadLetterSink(path string, cdc codec.JSONCodec) *FileDeadLetterSink {
+	return &FileDeadLetterSink{path: path, cdc: cdc}
+}
+
+// Put appends letter to the file
+func (s *FileDeadLetterSink) Put(letter DeadLetter) error {
+	line := fileDeadLetter{
+		ItemID:         letter.ItemID,
+		Priority:       letter.Priority,
+		IdempotencyKey: letter.IdempotencyKey,
+		Error:          letter.Error,
+		Codespace:      letter.Codespace,
+		Code:           letter.Code,
+		RawLog:         letter.RawLog,
+		TxHash:         letter.TxHash,
+		Attempts:       letter.Attempts,
+		FailedAt:       letter.FailedAt,
+	}
+	for _, msg := range letter.Msgs {
+		bz, err := s.cdc.MarshalInterfaceJSON(msg)
+		if err != nil {
+			return err
+		}
+		line.Msgs = append(line.Msgs, bz)
+	}
+	return s.append(line)
+}
+
+// List returns the letters in the file that were not removed
+func (s *FileDeadLetterSink) List() ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := []fileDeadLetter{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxJournalLineBytes)
+	for scanner.Scan() {
+		var line fileDeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			loggerOrDefault(s.Logger).Warn("skipping invalid dead letter", F(FieldError, err))
+			continue
+		}
+		if line.Removed {
+			kept := lines[:0]
+			for _, l := range lines {
+				if l.ItemID != line.ItemID {
+					kept = append(kept, l)
+				}
+			}
+			lines = kept
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	letters := []DeadLetter{}
+	for _, line := range lines {
+		letter := DeadLetter{
+			ItemID:         line.ItemID,
+			Priority:       line.Priority,
+			IdempotencyKey: line.IdempotencyKey,
+			Error:          line.Error,
+			Codespace:      line.Codespace,
+			Code:           line.Code,
+			RawLog:         line.RawLog,
+			TxHash:         line.TxHash,
+			Attempts:       line.Attempts,
+			FailedAt:       line.FailedAt,
+		}
+		for _, bz := range line.Msgs {
+			var msg sdktypes.Msg
+			if err := s.cdc.UnmarshalInterfaceJSON(bz, &msg); err != nil {
+				return nil, fmt.Errorf("unable to decode dead letter msg of item %s: %w", line.ItemID, err)
+			}
+			letter.Msgs = append(letter.Msgs, msg)
+		}
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
+
+// Remove records the letter of the item with itemID as removed
+func (s *FileDeadLetterSink) Remove(itemID string) error {
+	return s.append(fileDeadLetter{ItemID: itemID, Removed: true})
+}
+
+func (s *FileDeadLetterSink) append(line fileDeadLetter) error {
+	bz, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(append(bz, '\n')); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// deadLetter puts the failed async item to DeadLetters if set
+func (w *Wallet) deadLetter(item MsgQueueItem, response *sdktypes.TxResponse, err error) {
+	if w.DeadLetters == nil || !item.Async || err == nil {
+		return
+	}
+	letter := DeadLetter{
+		ItemID:         item.ID,
+		Msgs:           item.GetMsgs(),
+		Priority:       item.Priority,
+		IdempotencyKey: item.IdempotencyKey,
+		Error:          err.Error(),
+		Attempts:       item.Attempts,
+		FailedAt:       time.Now(),
+		Callback:       item.Callback,
+	}
+	if response != nil {
+		letter.Codespace, letter.Code, letter.RawLog, letter.TxHash = response.Codespace, response.Code, response.RawLog, response.TxHash
+	}
+	if err := w.DeadLetters.Put(letter); err != nil {
+		w.logger().Error("unable to put failed msg to dead letters", F(FieldItemID, item.ID), F(FieldError, err))
+	}
+}
+
+// RequeueDeadLetter removes the letter of the item with itemID from DeadLetters and submits its msgs again
+// as a new async item, which is put back to DeadLetters if it fails again
+func (w *Wallet) RequeueDeadLetter(itemID string) error {
+	if w.DeadLetters == nil {
+		return fmt.Errorf("wallet has no dead letter sink")
+	}
+	letters, err := w.DeadLetters.List()
+	if err != nil {
+		return err
+	}
+	for _, letter := range letters {
+		if letter.ItemID == itemID {
+			return w.requeueDeadLetter(letter)
+		}
+	}
+	return fmt.Errorf("dead letter %s not found", itemID)
+}
+
+// RequeueDeadLetters requeues every letter in DeadLetters, returning the number of letters requeued
+func (w *Wallet) RequeueDeadLetters() (int, error) {
+	if w.DeadLetters == nil {
+		return 0, fmt.Errorf("wallet has no dead letter sink")
+	}
+	letters, err := w.DeadLetters.List()
+	if err != nil {
+		return 0, err
+	}
+	for i, letter := range letters {
+		if err := w.requeueDeadLetter(letter); err != nil {
+			return i, err
+		}
+	}
+	return len(letters), nil
+}
+
+func (w *Wallet) requeueDeadLetter(letter DeadLetter) error {
+	if len(letter.Msgs) == 0 {
+		return fmt.Errorf("dead letter %s has no msgs", letter.ItemID)
+	}
+	// the requeued item gets a new ID, as the journal records the failed item as done
+	item := MsgQueueItem{
+		ID:             uuid.New().String(),
+		Msgs:           letter.Msgs,
+		Async:          true,
+		Priority:       letter.Priority,
+		IdempotencyKey: letter.IdempotencyKey,
+		Callback:       letter.Callback,
+	}
+	// the failed result of the key is replaced by the result of the requeued item
+	w.forgetIdempotent(MsgQueueItem{ID: letter.ItemID, IdempotencyKey: letter.IdempotencyKey})
+	if err := w.DeadLetters.Remove(letter.ItemID); err != nil {
+		return err
+	}
+	if err := w.enqueue(item, false); err != nil {
+		w.deadLetter(item, nil, err)
+		return err
+	}
+	return nil
+}
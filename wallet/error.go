@@ -1,7 +1,79 @@
 package wallet
 
-import "fmt"
+import (
+	"fmt"
 
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+var (
+	ErrStatusNotOK  = fmt.Errorf("HTTP Status not 200")
+	ErrQueueFull    = fmt.Errorf("msg queue is full")
+	ErrWalletClosed = fmt.Errorf("wallet is closed")
+	// ErrTxTimedOut is returned for a tx that was not confirmed within the confirmation timeout,
+	// or that passed its timeout height without being committed
+	ErrTxTimedOut = fmt.Errorf("transaction error: transaction timed out")
+)
+
+// Sentinels of the common sdk errors that a TxError can be matched against with errors.Is
 var (
-	ErrStatusNotOK = fmt.Errorf("HTTP Status not 200")
+	ErrSequenceMismatch  = sdkerrors.ErrWrongSequence
+	ErrInsufficientFee   = sdkerrors.ErrInsufficientFee
+	ErrOutOfGas          = sdkerrors.ErrOutOfGas
+	ErrInsufficientFunds = sdkerrors.ErrInsufficientFunds
+	ErrTxInMempoolCache  = sdkerrors.ErrTxInMempoolCache
+	ErrMempoolFull       = sdkerrors.ErrMempoolIsFull
+	ErrTimeoutHeight     = sdkerrors.ErrTxTimeoutHeight
 )
+
+// abciError is an error registered with an ABCI codespace and code, such as the sdk errors
+type abciError interface {
+	error
+	Codespace() string
+	ABCICode() uint32
+}
+
+// TxError is the error of a tx that was rejected by CheckTx, or that failed when it was committed.
+// errors.Is matches it against any error registered with the same codespace and code,
+// such as ErrSequenceMismatch.
+type TxError struct {
+	Codespace string
+	Code      uint32
+	RawLog    string
+	TxHash    string
+	// Committed is true if the tx failed when it was committed, rather than being rejected by CheckTx
+	Committed bool
+}
+
+// NewTxError returns the error of the failed tx with response
+func NewTxError(response *sdktypes.TxResponse, committed bool) *TxError {
+	return &TxError{
+		Codespace: response.Codespace,
+		Code:      response.Code,
+		RawLog:    response.RawLog,
+		TxHash:    response.TxHash,
+		Committed: committed,
+	}
+}
+
+func (e *TxError) Error() string {
+	if e.Committed {
+		return fmt.Sprintf("transaction error: transaction %s failed with codespace: %s, code: %d, raw_log: %s", e.TxHash, e.Codespace, e.Code, e.RawLog)
+	}
+	return fmt.Sprintf("broadcast failed with codespace: %s, code: %d, raw_log: %s", e.Codespace, e.Code, e.RawLog)
+}
+
+// Is returns true if target is an error registered with the codespace and code of e
+func (e *TxError) Is(target error) bool {
+	registered, ok := target.(abciError)
+	if !ok {
+		return false
+	}
+	return registered.Codespace() == e.Codespace && registered.ABCICode() == e.Code
+}
+
+// responseIs returns true if response failed with the registered error target
+func responseIs(response *sdktypes.TxResponse, target abciError) bool {
+	return response != nil && response.Code != 0 && response.Codespace == target.Codespace() && response.Code == target.ABCICode()
+}
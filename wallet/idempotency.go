@@ -0,0 +1,168 @@
+package wallet
+
+import (
+	"sync"
+	"time"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+// idempotencyPruneInterval is the min interval between prunes of the expired idempotency keys
+const idempotencyPruneInterval = time.Minute
+
+// WithIdempotencyKey submits msgs with an idempotency key. Submitting again with a key that was
+// already submitted within the retention window does not send the msgs again, and returns the
+// result of the first submission instead, waiting for it if it is still in progress.
+func WithIdempotencyKey(key string) SubmitOption {
+	return func(item *MsgQueueItem) {
+		item.IdempotencyKey = key
+	}
+}
+
+// idempotentSubmission is the first submission with an idempotency key, which is done once it has a result
+type idempotentSubmission struct {
+	itemID      string
+	done        chan struct{}
+	response    *sdktypes.TxResponse
+	err         error
+	completedAt time.Time
+}
+
+// IdempotencyKeys tracks the submissions with idempotency keys, and keeps their results
+// for the retention window after they are done
+type IdempotencyKeys struct {
+	mu          sync.Mutex
+	retention   time.Duration
+	submissions map[string]*idempotentSubmission
+	lastPruned  time.Time
+}
+
+// NewIdempotencyKeys returns idempotency keys that are retained for retention after their submissions are done
+func NewIdempotencyKeys(retention time.Duration) *IdempotencyKeys {
+	return &IdempotencyKeys{
+		retention:   retention,
+		submissions: make(map[string]*idempotentSubmission),
+		lastPruned:  time.Now(),
+	}
+}
+
+// Len returns the number of retained idempotency keys
+func (k *IdempotencyKeys) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.submissions)
+}
+
+// begin records the submission of the item with itemID and key, unless there is already
+// a submission with key, which is returned instead
+func (k *IdempotencyKeys) begin(key string, itemID string) (*idempotentSubmission, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.prune()
+	if existing, ok := k.submissions[key]; ok {
+		return existing, true
+	}
+	k.submissions[key] = &idempotentSubmission{itemID: itemID, done: make(chan struct{})}
+	return nil, false
+}
+
+// complete records the result of the submission of the item with itemID and key.
+// Without a retention window, the key is forgotten once its waiters have the result.
+// Returns false if the submission with key is a different item, or already done.
+func (k *IdempotencyKeys) complete(key string, itemID string, response *sdktypes.TxResponse, err error) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	submission, ok := k.submissions[key]
+	if !ok || submission.itemID != itemID || !submission.completedAt.IsZero() {
+		return false
+	}
+	submission.response, submission.err, submission.completedAt = response, err, time.Now()
+	close(submission.done)
+	if k.retention <= 0 {
+		delete(k.submissions, key)
+	}
+	return true
+}
+
+// restore restores the result of a submission done at completedAt, unless it has expired
+func (k *IdempotencyKeys) restore(key string, itemID string, response *sdktypes.TxResponse, err error, completedAt time.Time) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if time.Since(completedAt) > k.retention {
+		return false
+	}
+	done := make(chan struct{})
+	close(done)
+	k.submissions[key] = &idempotentSubmission{itemID: itemID, done: done, response: response, err: err, completedAt: completedAt}
+	return true
+}
+
+// forget forgets the submission of the item with itemID and key, which was never accepted
+func (k *IdempotencyKeys) forget(key string, itemID string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if submission, ok := k.submissions[key]; ok && submission.itemID == itemID {
+		delete(k.submissions, key)
+	}
+}
+
+// prune removes the keys of the submissions that were done before the retention window
+func (k *IdempotencyKeys) prune() {
+	if time.Since(k.lastPruned) < idempotencyPruneInterval {
+		return
+	}
+	k.lastPruned = time.Now()
+	for key, submission := range k.submissions {
+		if !submission.completedAt.IsZero() && time.Since(submission.completedAt) > k.retention {
+			delete(k.submissions, key)
+		}
+	}
+}
+
+// deduplicate records the submission of item if it has an idempotency key,
+// returning the earlier submission with the same key if there is one
+func (w *Wallet) deduplicate(item MsgQueueItem) (*idempotentSubmission, bool) {
+	if item.IdempotencyKey == "" || w.Idempotency == nil {
+		return nil, false
+	}
+	return w.Idempotency.begin(item.IdempotencyKey, item.ID)
+}
+
+// forgetIdempotent forgets the submission of item if it has an idempotency key, as it was never accepted
+func (w *Wallet) forgetIdempotent(item MsgQueueItem) {
+	if item.IdempotencyKey == "" || w.Idempotency == nil {
+		return
+	}
+	w.Idempotency.forget(item.IdempotencyKey, item.ID)
+}
+
+// waitIdempotent waits for the result of submission, or until the wallet is stopped
+func (w *Wallet) waitIdempotent(submission *idempotentSubmission) (*sdktypes.TxResponse, error) {
+	select {
+	case <-submission.done:
+		return submission.response, submission.err
+	case <-w.StopChannel:
+		return nil, ErrWalletClosed
+	}
+}
+
+// completeIdempotent records the result of item if it has an idempotency key,
+// persisting it to Journal if set. Only the first result of an item is recorded.
+func (w *Wallet) completeIdempotent(item MsgQueueItem, response *sdktypes.TxResponse, err error) {
+	if item.IdempotencyKey == "" || w.Idempotency == nil {
+		return
+	}
+	if !w.Idempotency.complete(item.IdempotencyKey, item.ID, response, err) {
+		return
+	}
+
+	event := JournalEvent{Type: JournalIdempotent, ItemIDs: []string{item.ID}, IdempotencyKey: item.IdempotencyKey, Response: journaledResponse(response)}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	w.appendJournal(event)
+}
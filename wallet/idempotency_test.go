@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestIdempotencyKeysBegin(t *testing.T) {
+	k := NewIdempotencyKeys(time.Minute)
+	if _, ok := k.begin("key", "a"); ok {
+		t.Fatal("expected first submission with key to begin")
+	}
+	existing, ok := k.begin("key", "b")
+	if !ok || existing.itemID != "a" {
+		t.Fatalf("expected submission of item a, got %+v", existing)
+	}
+}
+
+func TestIdempotencyKeysComplete(t *testing.T) {
+	k := NewIdempotencyKeys(time.Minute)
+	k.begin("key", "a")
+	if k.complete("key", "b", nil, nil) {
+		t.Fatal("expected a different item not to complete the submission")
+	}
+
+	response := &sdktypes.TxResponse{TxHash: "TX1"}
+	if !k.complete("key", "a", response, nil) {
+		t.Fatal("expected submission to complete")
+	}
+	if k.complete("key", "a", nil, errors.New("failed")) {
+		t.Fatal("expected submission to only complete once")
+	}
+
+	existing, ok := k.begin("key", "b")
+	if !ok {
+		t.Fatal("expected key to be retained")
+	}
+	select {
+	case <-existing.done:
+	default:
+		t.Fatal("expected submission to be done")
+	}
+	if existing.response != response || existing.err != nil {
+		t.Fatalf("expected first result, got %v, %v", existing.response, existing.err)
+	}
+}
+
+func TestIdempotencyKeysWithoutRetention(t *testing.T) {
+	k := NewIdempotencyKeys(0)
+	k.begin("key", "a")
+	waiter, _ := k.begin("key", "b")
+	k.complete("key", "a", nil, nil)
+
+	select {
+	case <-waiter.done:
+	default:
+		t.Fatal("expected waiter to have the result")
+	}
+	if k.Len() != 0 {
+		t.Fatalf("expected key to be forgotten, got %d keys", k.Len())
+	}
+	if _, ok := k.begin("key", "c"); ok {
+		t.Fatal("expected key to be submitted again")
+	}
+}
+
+func TestIdempotencyKeysForget(t *testing.T) {
+	k := NewIdempotencyKeys(time.Minute)
+	k.begin("key", "a")
+	k.forget("key", "b")
+	if k.Len() != 1 {
+		t.Fatal("expected a different item not to forget the key")
+	}
+	k.forget("key", "a")
+	if k.Len() != 0 {
+		t.Fatal("expected key to be forgotten")
+	}
+}
+
+func TestIdempotencyKeysRestore(t *testing.T) {
+	k := NewIdempotencyKeys(time.Minute)
+	if k.restore("expired", "a", nil, nil, time.Now().Add(-time.Hour)) {
+		t.Fatal("expected expired result not to be restored")
+	}
+	if !k.restore("key", "a", nil, errors.New("failed"), time.Now()) {
+		t.Fatal("expected result to be restored")
+	}
+
+	existing, ok := k.begin("key", "b")
+	if !ok || existing.itemID != "a" || existing.err == nil {
+		t.Fatalf("expected restored result of item a, got %+v", existing)
+	}
+	select {
+	case <-existing.done:
+	default:
+		t.Fatal("expected restored submission to be done")
+	}
+}
@@ -0,0 +1,498 @@
+package wallet
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	cmttypes "github.com/cometbft/cometbft/types"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+// JournalEventType is the type of a journal event
+type JournalEventType string
+
+const (
+	// JournalEnqueued records an item pushed to the msg queue, with its msgs
+	JournalEnqueued = JournalEventType("enqueued")
+	// JournalSigned records a tx signed for items, before it is broadcasted
+	JournalSigned = JournalEventType("signed")
+	// JournalBroadcast records a tx accepted by CheckTx
+	JournalBroadcast = JournalEventType("broadcast")
+	// JournalRejected records a tx that was rejected by CheckTx or could not be broadcasted, which is never confirmed
+	JournalRejected = JournalEventType("rejected")
+	// JournalConfirmed records a tx that is no longer pending, whether it was committed or not
+	JournalConfirmed = JournalEventType("confirmed")
+	// JournalDone records items that were responded to for the last time, and are never sent again
+	JournalDone = JournalEventType("done")
+	// JournalIdempotent records the result of an item submitted with an idempotency key
+	JournalIdempotent = JournalEventType("idempotent")
+)
+
+// JournalEvent is an entry of the journal
+type JournalEvent struct {
+	Type           JournalEventType     `json:"type"`
+	Time           time.Time            `json:"time"`
+	ItemIDs        []string             `json:"item_ids,omitempty"`
+	Msgs           []json.RawMessage    `json:"msgs,omitempty"`
+	Priority       Priority             `json:"priority,omitempty"`
+	Async          bool                 `json:"async,omitempty"`
+	Hash           string               `json:"hash,omitempty"`
+	Sequence       uint64               `json:"sequence,omitempty"`
+	TimeoutHeight  uint64               `json:"timeout_height,omitempty"`
+	IdempotencyKey string               `json:"idempotency_key,omitempty"`
+	Response       *sdktypes.TxResponse `json:"response,omitempty"`
+	Error          string               `json:"error,omitempty"`
+}
+
+// Journal is a write-ahead log of the msgs submitted to a wallet and of the txs they were sent in,
+// so that after a restart, the wallet can resume confirming the txs that were broadcasted,
+// and resubmit the msgs that never were
+type Journal interface {
+	// Append durably records event
+	Append(event JournalEvent) error
+	// Replay calls fn for each recorded event, in the order they were appended
+	Replay(fn func(JournalEvent) error) error
+	// Compact replaces all recorded events with events
+	Compact(events []JournalEvent) error
+	// Close closes the journal
+	Close() error
+}
+
+// FileJournal is a Journal that appends events to a file as JSON lines, syncing the file after each event
+type FileJournal struct {
+	// Logger logs the invalid events that are skipped if set, otherwise they are logged with DefaultLogger
+	Logger Logger
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+}
+
+// maxJournalLineBytes is the max size of an encoded journal event
+const maxJournalLineBytes = 64 * 1024 * 1024
+
+// OpenFileJournal opens the journal file at path, creating it if it does not exist
+func OpenFileJournal(path string) (*FileJournal, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileJournal{path: path, file: file}, nil
+}
+
+// Append appends event to the file
+func (j *FileJournal) Append(event JournalEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+// Replay calls fn for each event in the file. Lines that cannot be decoded,
+// such as one that was partially written before a crash, are skipped.
+func (j *FileJournal) Replay(fn func(JournalEvent) error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	file, err := os.Open(j.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxJournalLineBytes)
+	for scanner.Scan() {
+		var event JournalEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			loggerOrDefault(j.Logger).Warn("skipping invalid journal event", F(FieldError, err))
+			continue
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Compact atomically replaces the file with one that only has events
+func (j *FileJournal) Compact(events []JournalEvent) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	tmpPath := j.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		writer.Write(append(line, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, j.path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	j.file.Close()
+	j.file = file
+	return nil
+}
+
+// Close closes the file
+func (j *FileJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// appendJournal appends event to Journal if set, logging any error
+func (w *Wallet) appendJournal(event JournalEvent) {
+	if w.Journal == nil {
+		return
+	}
+	event.Time = time.Now()
+	if err := w.Journal.Append(event); err != nil {
+		w.logger().Error("unable to append event to journal", F("event", event.Type), F(FieldError, err))
+	}
+}
+
+// journalEnqueued records item before it is pushed to the msg queue
+func (w *Wallet) journalEnqueued(item MsgQueueItem) error {
+	if w.Journal == nil {
+		return nil
+	}
+	cdc := codec.NewProtoCodec(w.ClientCtx.InterfaceRegistry)
+	msgs := []json.RawMessage{}
+	for _, msg := range item.GetMsgs() {
+		bz, err := cdc.MarshalInterfaceJSON(msg)
+		if err != nil {
+			return fmt.Errorf("unable to journal msg: %w", err)
+		}
+		msgs = append(msgs, bz)
+	}
+	return w.Journal.Append(JournalEvent{
+		Type:           JournalEnqueued,
+		Time:           time.Now(),
+		ItemIDs:        []string{item.ID},
+		Msgs:           msgs,
+		Priority:       item.Priority,
+		Async:          item.Async,
+		IdempotencyKey: item.IdempotencyKey,
+	})
+}
+
+// journalSigned records tx signed for items, before it is broadcasted
+func (w *Wallet) journalSigned(tx authsigning.Tx, items []MsgQueueItem) {
+	if w.Journal == nil {
+		return
+	}
+	hash, err := txHash(tx)
+	if err != nil {
+		w.logger().Error("unable to journal signed tx", F(FieldError, err))
+		return
+	}
+	sequence, _ := txSequence(tx)
+	w.appendJournal(JournalEvent{
+		Type:          JournalSigned,
+		ItemIDs:       itemIDs(items),
+		Hash:          hash,
+		Sequence:      sequence,
+		TimeoutHeight: tx.GetTimeoutHeight(),
+	})
+}
+
+// journalBroadcast records the tx with hash accepted by CheckTx
+func (w *Wallet) journalBroadcast(hash string) {
+	w.appendJournal(JournalEvent{Type: JournalBroadcast, Hash: hash})
+}
+
+// journalRejected records that tx was rejected by CheckTx or could not be broadcasted,
+// so that its items are only restored with the tx they are sent again in, if any
+func (w *Wallet) journalRejected(tx authsigning.Tx) {
+	if w.Journal == nil {
+		return
+	}
+	hash, err := txHash(tx)
+	if err != nil {
+		w.logger().Error("unable to journal rejected tx", F(FieldError, err))
+		return
+	}
+	w.appendJournal(JournalEvent{Type: JournalRejected, Hash: hash})
+}
+
+// journalDone records that items are never sent again
+func (w *Wallet) journalDone(items []MsgQueueItem) {
+	if len(items) == 0 {
+		return
+	}
+	w.appendJournal(JournalEvent{Type: JournalDone, ItemIDs: itemIDs(items)})
+}
+
+// removePending removes the pending tx with hash, recording it as confirmed
+func (w *Wallet) removePending(hash string) (TxItems, bool) {
+	txItems, ok := w.PendingTxs.Remove(hash)
+	if ok {
+		w.appendJournal(JournalEvent{Type: JournalConfirmed, Hash: hash})
+	}
+	return txItems, ok
+}
+
+// removeCommitted removes the pending tx committed with response, recording it as confirmed with
+// its response, so that the items of a successful tx are never resubmitted, even if the wallet
+// stops before they are done
+func (w *Wallet) removeCommitted(response *sdktypes.TxResponse) (TxItems, bool) {
+	txItems, ok := w.PendingTxs.Remove(response.TxHash)
+	if ok {
+		w.appendJournal(JournalEvent{Type: JournalConfirmed, Hash: response.TxHash, Response: journaledResponse(response)})
+	}
+	return txItems, ok
+}
+
+// journaledResponse returns the fields of response that are recorded in the journal
+func journaledResponse(response *sdktypes.TxResponse) *sdktypes.TxResponse {
+	if response == nil {
+		return nil
+	}
+	return &sdktypes.TxResponse{
+		TxHash:    response.TxHash,
+		Height:    response.Height,
+		Codespace: response.Codespace,
+		Code:      response.Code,
+		RawLog:    response.RawLog,
+	}
+}
+
+// txHash returns the hash of tx, as it is committed by the node
+func txHash(tx authsigning.Tx) (string, error) {
+	txBytes, err := GetTxConfig().TxEncoder()(tx)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(cmttypes.Tx(txBytes).Hash())), nil
+}
+
+func itemIDs(items []MsgQueueItem) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+type journaledTx struct {
+	event     JournalEvent
+	broadcast bool
+}
+
+// ReplayJournal restores the state recorded in Journal after a restart. Txs that were signed but not
+// confirmed or rejected are confirmed again, including those that may or may not have been broadcasted,
+// and the items that were not sent in any such tx are resubmitted. The items of a tx that may not have
+// been broadcasted are resubmitted once it provably expires if RebroadcastTimedOutTxs is set, which requires TxTimeoutHeight to be set. Items that were signed again after
+// their tx was rejected are only restored with the last tx they were signed in, and the items of a tx that
+// was committed successfully are never resubmitted. Restored items have no callbacks. The journal is then
+// compacted to the restored state.
+func (w *Wallet) ReplayJournal() error {
+	if w.Journal == nil {
+		return nil
+	}
+
+	enqueued := map[string]JournalEvent{}
+	order := []string{}
+	ordered := map[string]bool{}
+	signed := map[string]*journaledTx{}
+	signedOrder := []string{}
+	// hash of the last tx that each item was signed in
+	lastSigned := map[string]string{}
+	idempotent := map[string]JournalEvent{}
+	// confirmed event of the successful tx that each item was last signed in
+	committed := map[string]JournalEvent{}
+	err := w.Journal.Replay(func(event JournalEvent) error {
+		switch event.Type {
+		case JournalIdempotent:
+			idempotent[event.IdempotencyKey] = event
+		case JournalEnqueued:
+			if len(event.ItemIDs) == 1 {
+				if !ordered[event.ItemIDs[0]] {
+					ordered[event.ItemIDs[0]] = true
+					order = append(order, event.ItemIDs[0])
+				}
+				enqueued[event.ItemIDs[0]] = event
+			}
+		case JournalSigned:
+			signed[event.Hash] = &journaledTx{event: event}
+			signedOrder = append(signedOrder, event.Hash)
+			for _, id := range event.ItemIDs {
+				lastSigned[id] = event.Hash
+			}
+		case JournalBroadcast:
+			if tx, ok := signed[event.Hash]; ok {
+				tx.broadcast = true
+			}
+		case JournalConfirmed:
+			if tx, ok := signed[event.Hash]; ok && event.Response != nil && event.Response.Code == 0 {
+				// the items of a successful tx are done, whether or not they were responded to
+				for _, id := range tx.event.ItemIDs {
+					if lastSigned[id] == event.Hash {
+						committed[id] = event
+					}
+				}
+			}
+			delete(signed, event.Hash)
+		case JournalRejected:
+			delete(signed, event.Hash)
+		case JournalDone:
+			for _, id := range event.ItemIDs {
+				delete(enqueued, id)
+				delete(committed, id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the items of successful txs that were not yet done keep their results for their idempotency keys
+	for id, event := range committed {
+		enqueuedEvent, ok := enqueued[id]
+		if !ok {
+			continue
+		}
+		delete(enqueued, id)
+		key := enqueuedEvent.IdempotencyKey
+		if _, ok := idempotent[key]; key != "" && !ok {
+			idempotent[key] = JournalEvent{Type: JournalIdempotent, Time: event.Time, ItemIDs: []string{id}, IdempotencyKey: key, Response: event.Response}
+		}
+	}
+
+	cdc := codec.NewProtoCodec(w.ClientCtx.InterfaceRegistry)
+	items := map[string]MsgQueueItem{}
+	for id, event := range enqueued {
+		item := MsgQueueItem{ID: id, Priority: event.Priority, Async: true, IdempotencyKey: event.IdempotencyKey}
+		for _, bz := range event.Msgs {
+			var msg sdktypes.Msg
+			if err := cdc.UnmarshalInterfaceJSON(bz, &msg); err != nil {
+				return fmt.Errorf("unable to restore journaled msg of item %s: %w", id, err)
+			}
+			item.Msgs = append(item.Msgs, msg)
+		}
+		items[id] = item
+	}
+
+	// restore the pending txs, and the items sent in them
+	txEvents := []JournalEvent{}
+	pendingItems := map[string]bool{}
+	pending := []TxItems{}
+	for _, hash := range signedOrder {
+		tx, ok := signed[hash]
+		if !ok {
+			continue
+		}
+		delete(signed, hash)
+		txItems := TxItems{Hash: hash, Sequence: tx.event.Sequence, TimeoutHeight: tx.event.TimeoutHeight, CreatedAt: tx.event.Time, Unbroadcast: !tx.broadcast}
+		for _, id := range tx.event.ItemIDs {
+			if item, ok := items[id]; ok && lastSigned[id] == hash {
+				txItems.Items = append(txItems.Items, item)
+				pendingItems[id] = true
+			}
+		}
+		if len(txItems.Items) == 0 {
+			// every item of the tx is done, or was sent again in a later tx
+			continue
+		}
+		pending = append(pending, txItems)
+		txEvents = append(txEvents, tx.event)
+		if tx.broadcast {
+			txEvents = append(txEvents, JournalEvent{Type: JournalBroadcast, Time: tx.event.Time, Hash: hash})
+		}
+	}
+
+	// the enqueued events must come before the txs they were signed in
+	live := []JournalEvent{}
+	resubmit := []MsgQueueItem{}
+	for _, id := range order {
+		event, ok := enqueued[id]
+		if !ok {
+			continue
+		}
+		live = append(live, event)
+		if !pendingItems[id] {
+			resubmit = append(resubmit, items[id])
+		}
+	}
+	live = append(live, txEvents...)
+
+	// restore the idempotency keys of the results within the retention window, and of the items in progress
+	if w.Idempotency != nil {
+		for key, event := range idempotent {
+			var resultErr error
+			if event.Error != "" {
+				resultErr = errors.New(event.Error)
+			}
+			if len(event.ItemIDs) == 1 && w.Idempotency.restore(key, event.ItemIDs[0], event.Response, resultErr, event.Time) {
+				live = append(live, event)
+			}
+		}
+		for _, item := range items {
+			if item.IdempotencyKey != "" {
+				w.Idempotency.begin(item.IdempotencyKey, item.ID)
+			}
+		}
+	}
+	if err := w.Journal.Compact(live); err != nil {
+		return err
+	}
+
+	if len(pending) > 0 || len(resubmit) > 0 {
+		w.logger().Info("replayed journal", F("pending_tx_count", len(pending)), F("resubmit_count", len(resubmit)))
+	}
+	for _, txItems := range pending {
+		w.PendingTxs.Add(txItems)
+		w.sendConfirmTransaction(txItems)
+	}
+	for _, item := range resubmit {
+		dropped, err := w.MsgQueue.Push(item)
+		if dropped != nil {
+			w.EnqueueMsgResponse(*dropped, nil, ErrQueueFull)
+		}
+		if err != nil {
+			w.EnqueueMsgResponse(item, nil, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package wallet
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// newJournalWallet returns a wallet with a FileJournal that has events
+func newJournalWallet(t *testing.T, events ...JournalEvent) *Wallet {
+	t.Helper()
+	registry := codectypes.NewInterfaceRegistry()
+	banktypes.RegisterInterfaces(registry)
+
+	journal, err := OpenFileJournal(filepath.Join(t.TempDir(), "journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { journal.Close() })
+	for _, event := range events {
+		if err := journal.Append(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &Wallet{
+		ClientCtx:                 client.Context{InterfaceRegistry: registry},
+		MsgQueue:                  NewMsgQueue(nil, 0, OverflowBlock),
+		PendingTxs:                NewPendingTxs(),
+		ConfirmTransactionChannel: make(chan TxItems, 16),
+		StopChannel:               make(chan int, 3),
+		Journal:                   journal,
+	}
+}
+
+// enqueuedEvent returns the event of the item with id, with a MsgSend
+func enqueuedEvent(t *testing.T, id string) JournalEvent {
+	t.Helper()
+	registry := codectypes.NewInterfaceRegistry()
+	banktypes.RegisterInterfaces(registry)
+	msg := &banktypes.MsgSend{FromAddress: "from", ToAddress: "to", Amount: sdktypes.NewCoins(sdktypes.NewInt64Coin("swth", 1))}
+	bz, err := codec.NewProtoCodec(registry).MarshalInterfaceJSON(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return JournalEvent{Type: JournalEnqueued, ItemIDs: []string{id}, Msgs: []json.RawMessage{bz}, Async: true}
+}
+
+// pendingByHash returns the pending txs of w by hash
+func pendingByHash(w *Wallet) map[string]TxItems {
+	pending := map[string]TxItems{}
+	for _, txItems := range w.PendingTxs.List() {
+		pending[txItems.Hash] = txItems
+	}
+	return pending
+}
+
+// queuedIDs returns the IDs of the items in the msg queue of w
+func queuedIDs(w *Wallet) []string {
+	return itemIDs(w.MsgQueue.Drain())
+}
+
+func TestReplayJournalSkipsRejectedTxs(t *testing.T) {
+	w := newJournalWallet(t,
+		enqueuedEvent(t, "a"),
+		JournalEvent{Type: JournalSigned, ItemIDs: []string{"a"}, Hash: "TX1"},
+		JournalEvent{Type: JournalRejected, Hash: "TX1"},
+	)
+	if err := w.ReplayJournal(); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.PendingTxs.Len() != 0 {
+		t.Fatalf("expected no pending txs, got %d", w.PendingTxs.Len())
+	}
+	if ids := queuedIDs(w); len(ids) != 1 || ids[0] != "a" {
+		t.Fatalf("expected item a to be resubmitted, got %v", ids)
+	}
+}
+
+func TestReplayJournalRestoresItemsWithLastSignedTx(t *testing.T) {
+	w := newJournalWallet(t,
+		enqueuedEvent(t, "a"),
+		enqueuedEvent(t, "b"),
+		JournalEvent{Type: JournalSigned, ItemIDs: []string{"a", "b"}, Hash: "TX1"},
+		JournalEvent{Type: JournalBroadcast, Hash: "TX1"},
+		JournalEvent{Type: JournalSigned, ItemIDs: []string{"a"}, Hash: "TX2"},
+		JournalEvent{Type: JournalBroadcast, Hash: "TX2"},
+	)
+	if err := w.ReplayJournal(); err != nil {
+		t.Fatal(err)
+	}
+
+	pending := pendingByHash(w)
+	if ids := itemIDs(pending["TX1"].Items); len(ids) != 1 || ids[0] != "b" {
+		t.Fatalf("expected TX1 to only have item b, got %v", ids)
+	}
+	if ids := itemIDs(pending["TX2"].Items); len(ids) != 1 || ids[0] != "a" {
+		t.Fatalf("expected TX2 to only have item a, got %v", ids)
+	}
+	if ids := queuedIDs(w); len(ids) != 0 {
+		t.Fatalf("expected no resubmitted items, got %v", ids)
+	}
+}
+
+func TestReplayJournalSkipsTxsWithoutItems(t *testing.T) {
+	w := newJournalWallet(t,
+		enqueuedEvent(t, "a"),
+		JournalEvent{Type: JournalSigned, ItemIDs: []string{"a"}, Hash: "TX1"},
+		JournalEvent{Type: JournalBroadcast, Hash: "TX1"},
+		JournalEvent{Type: JournalDone, ItemIDs: []string{"a"}},
+	)
+	if err := w.ReplayJournal(); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.PendingTxs.Len() != 0 {
+		t.Fatalf("expected no pending txs, got %d", w.PendingTxs.Len())
+	}
+	if ids := queuedIDs(w); len(ids) != 0 {
+		t.Fatalf("expected no resubmitted items, got %v", ids)
+	}
+}
+
+func TestReplayJournalDedupesRequeuedItems(t *testing.T) {
+	w := newJournalWallet(t,
+		enqueuedEvent(t, "a"),
+		JournalEvent{Type: JournalDone, ItemIDs: []string{"a"}},
+		enqueuedEvent(t, "a"),
+	)
+	if err := w.ReplayJournal(); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := queuedIDs(w); len(ids) != 1 || ids[0] != "a" {
+		t.Fatalf("expected item a to be resubmitted once, got %v", ids)
+	}
+
+	// the compacted journal has the item once
+	enqueued := 0
+	err := w.Journal.Replay(func(event JournalEvent) error {
+		if event.Type == JournalEnqueued {
+			enqueued++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enqueued != 1 {
+		t.Fatalf("expected 1 enqueued event after compaction, got %d", enqueued)
+	}
+}
+
+func TestReplayJournalRestoresUnbroadcastTxs(t *testing.T) {
+	w := newJournalWallet(t,
+		enqueuedEvent(t, "a"),
+		enqueuedEvent(t, "b"),
+		JournalEvent{Type: JournalSigned, ItemIDs: []string{"a"}, Hash: "TX1", TimeoutHeight: 100},
+		JournalEvent{Type: JournalSigned, ItemIDs: []string{"b"}, Hash: "TX2", TimeoutHeight: 100},
+		JournalEvent{Type: JournalBroadcast, Hash: "TX2"},
+	)
+	if err := w.ReplayJournal(); err != nil {
+		t.Fatal(err)
+	}
+
+	pending := pendingByHash(w)
+	if tx, ok := pending["TX1"]; !ok || !tx.Unbroadcast {
+		t.Fatalf("expected TX1 to be restored as unbroadcast, got %+v", tx)
+	}
+	if tx, ok := pending["TX2"]; !ok || tx.Unbroadcast {
+		t.Fatalf("expected TX2 to be restored as broadcast, got %+v", tx)
+	}
+	if len(w.ConfirmTransactionChannel) != 2 {
+		t.Fatalf("expected 2 txs to be confirmed, got %d", len(w.ConfirmTransactionChannel))
+	}
+}
+
+func TestReplayJournalKeepsState(t *testing.T) {
+	w := newJournalWallet(t,
+		enqueuedEvent(t, "a"),
+		enqueuedEvent(t, "b"),
+		JournalEvent{Type: JournalSigned, ItemIDs: []string{"a"}, Hash: "TX1"},
+		JournalEvent{Type: JournalBroadcast, Hash: "TX1"},
+	)
+	if err := w.ReplayJournal(); err != nil {
+		t.Fatal(err)
+	}
+
+	// replaying the compacted journal restores the same state
+	restarted := newJournalWallet(t)
+	restarted.Journal = w.Journal
+	if err := restarted.ReplayJournal(); err != nil {
+		t.Fatal(err)
+	}
+	tx, ok := pendingByHash(restarted)["TX1"]
+	if !ok || tx.Unbroadcast {
+		t.Fatalf("expected TX1 to be restored as broadcast, got %+v", tx)
+	}
+	if ids := itemIDs(tx.Items); len(ids) != 1 || ids[0] != "a" {
+		t.Fatalf("expected TX1 to have item a, got %v", ids)
+	}
+	if ids := queuedIDs(restarted); len(ids) != 1 || ids[0] != "b" {
+		t.Fatalf("expected item b to be resubmitted, got %v", ids)
+	}
+}
+
+func TestReplayJournalSkipsItemsOfCommittedTxs(t *testing.T) {
+	keyed := enqueuedEvent(t, "b")
+	keyed.IdempotencyKey = "key"
+	w := newJournalWallet(t,
+		enqueuedEvent(t, "a"),
+		keyed,
+		enqueuedEvent(t, "c"),
+		JournalEvent{Type: JournalSigned, ItemIDs: []string{"a", "b"}, Hash: "TX1"},
+		JournalEvent{Type: JournalBroadcast, Hash: "TX1"},
+		JournalEvent{Type: JournalSigned, ItemIDs: []string{"c"}, Hash: "TX2"},
+		JournalEvent{Type: JournalBroadcast, Hash: "TX2"},
+		// the wallet stops after the txs are confirmed, before their items are done
+		JournalEvent{Type: JournalConfirmed, Hash: "TX1", Response: &sdktypes.TxResponse{TxHash: "TX1", Height: 10}, Time: time.Now()},
+		JournalEvent{Type: JournalConfirmed, Hash: "TX2", Response: &sdktypes.TxResponse{TxHash: "TX2", Height: 10, Code: 5}},
+	)
+	w.Idempotency = NewIdempotencyKeys(time.Minute)
+	if err := w.ReplayJournal(); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.PendingTxs.Len() != 0 {
+		t.Fatalf("expected no pending txs, got %d", w.PendingTxs.Len())
+	}
+	if ids := queuedIDs(w); len(ids) != 1 || ids[0] != "c" {
+		t.Fatalf("expected only the item of the failed tx to be resubmitted, got %v", ids)
+	}
+	existing, ok := w.Idempotency.begin("key", "d")
+	if !ok || existing.itemID != "b" || existing.response == nil || existing.response.TxHash != "TX1" {
+		t.Fatalf("expected result of item b to be restored, got %+v", existing)
+	}
+}
@@ -0,0 +1,154 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+// shutdownPollInterval is the interval between checks for the queue to be flushed and txs to be confirmed
+const shutdownPollInterval = 100 * time.Millisecond
+
+// Lifecycle tracks the goroutines of a wallet, so that they can all be stopped on shutdown
+type Lifecycle struct {
+	closing     chan struct{}
+	closingOnce sync.Once
+	stopOnce    sync.Once
+	goroutines  sync.WaitGroup
+}
+
+// NewLifecycle returns the lifecycle of a running wallet
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{closing: make(chan struct{})}
+}
+
+// Go runs fn in a goroutine that is waited for on shutdown
+func (l *Lifecycle) Go(fn func()) {
+	l.goroutines.Add(1)
+	go func() {
+		defer l.goroutines.Done()
+		fn()
+	}()
+}
+
+// closingChannel returns a channel that is closed once the wallet starts shutting down.
+// Without a lifecycle, the wallet never shuts down.
+func (w *Wallet) closingChannel() <-chan struct{} {
+	if w.Lifecycle == nil {
+		return nil
+	}
+	return w.Lifecycle.closing
+}
+
+// Shutdown stops accepting new msgs, flushes the msg queue, and waits until every broadcasted tx is
+// confirmed or ctx is done. The msgs and txs left are then failed with ErrWalletClosed, and all of the
+// wallet's goroutines are stopped. Calling Shutdown or Disconnect again only fails the msgs and txs left.
+func (w *Wallet) Shutdown(ctx context.Context) error {
+	if w.Lifecycle == nil {
+		return fmt.Errorf("wallet has no lifecycle to shut down")
+	}
+	w.logger().Info("shutting down wallet")
+
+	// stop accepting new msgs, and flush the queued msgs without waiting for more
+	w.MsgQueue.Close()
+	w.Lifecycle.closingOnce.Do(func() {
+		close(w.Lifecycle.closing)
+	})
+
+	err := w.waitUntilSettled(ctx)
+	if err != nil {
+		w.logger().Warn("wallet shut down with msgs left", F("queue_depth", w.MsgQueue.Len()), F("pending_tx_count", w.PendingTxs.Len()), F(FieldError, err))
+	}
+
+	w.stop()
+	w.failRemaining()
+	return err
+}
+
+// waitUntilSettled waits until the msg queue is flushed and every broadcasted tx is confirmed,
+// returning the ctx error if ctx is done first
+func (w *Wallet) waitUntilSettled(ctx context.Context) error {
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for !w.settled() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// settled returns true if there are no msgs waiting to be sent and no txs waiting to be confirmed
+func (w *Wallet) settled() bool {
+	if w.MsgQueue.Len() > 0 || len(w.RetryBatchQueue) > 0 {
+		return false
+	}
+	if w.RetryBacklog != nil && w.RetryBacklog.Len() > 0 {
+		return false
+	}
+	if w.Pipeline != nil && w.Pipeline.Len() > 0 {
+		return false
+	}
+	if w.Finality != nil && w.Finality.Len() > 0 {
+		return false
+	}
+	return w.PendingTxs.Len() == 0
+}
+
+// stop stops all goroutines of the wallet, waiting for the ones that send txs to return
+func (w *Wallet) stop() {
+	w.Lifecycle.stopOnce.Do(func() {
+		close(w.StopChannel)
+		w.unregisterMetrics()
+		if w.Scheduler != nil {
+			w.Scheduler.Stop()
+		}
+		if w.ConfirmationBackend != nil {
+			w.ConfirmationBackend.Stop()
+		}
+		w.Lifecycle.goroutines.Wait()
+		if w.GRPCConn != nil {
+			w.GRPCConn.Close()
+		}
+	})
+}
+
+// failRemaining fails the msgs that were not sent and the txs that were not confirmed with ErrWalletClosed
+func (w *Wallet) failRemaining() {
+	for _, item := range w.MsgQueue.Drain() {
+		w.EnqueueMsgResponse(item, nil, ErrWalletClosed)
+	}
+	// the backlog is closed first, so that no more retries are queued once the queue is drained
+	if w.RetryBacklog != nil {
+		for _, items := range w.RetryBacklog.close() {
+			w.failBatch(items, ErrWalletClosed)
+		}
+	}
+	for {
+		select {
+		case items := <-w.RetryBatchQueue:
+			w.failBatch(items, ErrWalletClosed)
+			continue
+		default:
+		}
+		break
+	}
+	for _, txItems := range w.PendingTxs.List() {
+		if _, ok := w.removePending(txItems.Hash); !ok {
+			continue
+		}
+		endConfirmSpan(txItems, nil, ErrWalletClosed)
+		w.runCallback(&sdktypes.TxResponse{TxHash: txItems.Hash}, txItems.Items, ErrWalletClosed)
+	}
+	if w.Finality != nil {
+		for _, tx := range w.Finality.drain() {
+			w.runCallback(tx.response, tx.txItems.Items, ErrWalletClosed)
+		}
+	}
+}
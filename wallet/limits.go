@@ -0,0 +1,311 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+var (
+	// ErrRateLimited is returned for msgs submitted faster than the wallet's msg rate limit
+	ErrRateLimited = fmt.Errorf("msg rate limit exceeded")
+	// ErrRateBurstExceeded is returned for msgs that must be sent together but are more than the burst
+	// of the wallet's msg rate limit, which are never allowed, unlike msgs rejected with ErrRateLimited
+	ErrRateBurstExceeded = fmt.Errorf("msgs exceed the msg rate limit burst")
+	// ErrSpendLimitExceeded is matched by errors.Is for every SpendLimitError
+	ErrSpendLimitExceeded = fmt.Errorf("spend limit exceeded")
+)
+
+// ibcTransferTypeURL is the type URL of ibc-go's MsgTransfer, which is matched by type URL
+// so that ibc-go is not a dependency
+const ibcTransferTypeURL = "/ibc.applications.transfer.v1.MsgTransfer"
+
+// spendBucketSize is the resolution of the rolling spend windows
+const spendBucketSize = time.Minute
+
+// SpendLimit limits the amount of a denom sent out of the wallet within a rolling window
+type SpendLimit struct {
+	Denom  string
+	Amount sdkmath.Int
+	Window time.Duration
+}
+
+// SpendLimitError is returned for msgs that would send more than a spend limit allows
+type SpendLimitError struct {
+	Limit SpendLimit
+	// Spent is the amount already sent within the window
+	Spent sdkmath.Int
+	// Amount is the amount that the rejected msgs send
+	Amount sdkmath.Int
+}
+
+func (e *SpendLimitError) Error() string {
+	return fmt.Sprintf("%s: sending %s%s would exceed the limit of %s%s per %s, with %s%s already sent",
+		ErrSpendLimitExceeded, e.Amount, e.Limit.Denom, e.Limit.Amount, e.Limit.Denom, e.Limit.Window, e.Spent, e.Limit.Denom)
+}
+
+// Is returns true for ErrSpendLimitExceeded
+func (e *SpendLimitError) Is(target error) bool {
+	return target == ErrSpendLimitExceeded
+}
+
+// SpendLimiter tracks the outflows of MsgSend, MsgMultiSend and IBC MsgTransfer msgs by denom, including
+// those executed by an authz.MsgExec, rejecting msgs that would exceed a spend limit. Outflows are counted
+// when msgs are queued, and are not refunded if their txs fail. If a ledger path is set, the outflows are persisted to it,
+// so that limits such as a daily cap still apply after a restart.
+type SpendLimiter struct {
+	mu     sync.Mutex
+	limits []SpendLimit
+	path   string
+	// spent amounts by denom and by the start of each bucket, in unix seconds
+	spent map[string]map[int64]sdkmath.Int
+}
+
+// NewSpendLimiter returns a spend limiter with limits, loading the persisted outflows from the
+// ledger at path if there is one. Leave path empty to only track outflows in memory.
+func NewSpendLimiter(limits []SpendLimit, path string) (*SpendLimiter, error) {
+	l := &SpendLimiter{limits: limits, path: path, spent: make(map[string]map[int64]sdkmath.Int)}
+	if path == "" {
+		return l, nil
+	}
+	bz, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bz, &l.spent); err != nil {
+		return nil, fmt.Errorf("invalid spend ledger %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Spent returns the amount of denom sent within window
+func (l *SpendLimiter) Spent(denom string, window time.Duration) sdkmath.Int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.spentWithin(denom, window, time.Now())
+}
+
+// reserve counts coins as sent, unless that would exceed a limit, returning the time they were counted at
+func (l *SpendLimiter) reserve(coins sdktypes.Coins) (time.Time, error) {
+	now := time.Now()
+	if coins.IsZero() {
+		return now, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, limit := range l.limits {
+		amount := coins.AmountOf(limit.Denom)
+		if !amount.IsPositive() {
+			continue
+		}
+		spent := l.spentWithin(limit.Denom, limit.Window, now)
+		if spent.Add(amount).GT(limit.Amount) {
+			return now, &SpendLimitError{Limit: limit, Spent: spent, Amount: amount}
+		}
+	}
+
+	l.add(coins, now, false)
+	if err := l.save(now); err != nil {
+		// the msgs are rejected, so their coins are not counted
+		l.add(coins, now, true)
+		return now, err
+	}
+	return now, nil
+}
+
+// release uncounts coins that were reserved at reservedAt but never sent, from the bucket they were counted in
+func (l *SpendLimiter) release(coins sdktypes.Coins, reservedAt time.Time) error {
+	if coins.IsZero() {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.add(coins, reservedAt, true)
+	return l.save(time.Now())
+}
+
+// add adds coins to the bucket of at, or subtracts them from it
+func (l *SpendLimiter) add(coins sdktypes.Coins, at time.Time, subtract bool) {
+	bucket := at.Truncate(spendBucketSize).Unix()
+	for _, coin := range coins {
+		buckets, ok := l.spent[coin.Denom]
+		if !ok {
+			buckets = make(map[int64]sdkmath.Int)
+			l.spent[coin.Denom] = buckets
+		}
+		spent, ok := buckets[bucket]
+		if !ok {
+			spent = sdkmath.ZeroInt()
+		}
+		if subtract {
+			buckets[bucket] = sdkmath.MaxInt(spent.Sub(coin.Amount), sdkmath.ZeroInt())
+			continue
+		}
+		buckets[bucket] = spent.Add(coin.Amount)
+	}
+}
+
+func (l *SpendLimiter) spentWithin(denom string, window time.Duration, now time.Time) sdkmath.Int {
+	since := now.Add(-window).Unix()
+	spent := sdkmath.ZeroInt()
+	for bucket, amount := range l.spent[denom] {
+		if bucket+int64(spendBucketSize/time.Second) > since {
+			spent = spent.Add(amount)
+		}
+	}
+	return spent
+}
+
+// save prunes the buckets outside of every window, and persists the rest to the ledger if there is one
+func (l *SpendLimiter) save(now time.Time) error {
+	maxWindow := time.Duration(0)
+	for _, limit := range l.limits {
+		if limit.Window > maxWindow {
+			maxWindow = limit.Window
+		}
+	}
+	since := now.Add(-maxWindow - spendBucketSize).Unix()
+	for denom, buckets := range l.spent {
+		for bucket := range buckets {
+			if bucket < since {
+				delete(buckets, bucket)
+			}
+		}
+		if len(buckets) == 0 {
+			delete(l.spent, denom)
+		}
+	}
+
+	if l.path == "" {
+		return nil
+	}
+	bz, err := json.Marshal(l.spent)
+	if err != nil {
+		return err
+	}
+	tmpPath := l.path + ".tmp"
+	if err := os.WriteFile(tmpPath, bz, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, l.path)
+}
+
+// msgOutflows returns the coins sent out of the wallet by msgs, including the msgs executed by an authz.MsgExec
+func msgOutflows(msgs []sdktypes.Msg) (sdktypes.Coins, error) {
+	outflows := sdktypes.NewCoins()
+	for _, msg := range msgs {
+		switch msg := msg.(type) {
+		case *banktypes.MsgSend:
+			outflows = outflows.Add(msg.Amount...)
+		case *banktypes.MsgMultiSend:
+			for _, input := range msg.Inputs {
+				outflows = outflows.Add(input.Coins...)
+			}
+		case *authz.MsgExec:
+			execMsgs, err := msg.GetMessages()
+			if err != nil {
+				return nil, fmt.Errorf("unable to get msgs of MsgExec to check spend limits: %w", err)
+			}
+			execOutflows, err := msgOutflows(execMsgs)
+			if err != nil {
+				return nil, err
+			}
+			outflows = outflows.Add(execOutflows...)
+		default:
+			if sdktypes.MsgTypeURL(msg) == ibcTransferTypeURL {
+				if token, ok := ibcTransferToken(msg); ok {
+					outflows = outflows.Add(token)
+				}
+			}
+		}
+	}
+	return outflows, nil
+}
+
+// ibcTransferToken returns the Token field of an ibc-go MsgTransfer
+func ibcTransferToken(msg sdktypes.Msg) (sdktypes.Coin, bool) {
+	value := reflect.Indirect(reflect.ValueOf(msg))
+	if value.Kind() != reflect.Struct {
+		return sdktypes.Coin{}, false
+	}
+	field := value.FieldByName("Token")
+	if !field.IsValid() || !field.CanInterface() {
+		return sdktypes.Coin{}, false
+	}
+	token, ok := field.Interface().(sdktypes.Coin)
+	if !ok || !token.Amount.IsPositive() {
+		return sdktypes.Coin{}, false
+	}
+	return token, true
+}
+
+// checkLimits checks item against the msg rate limit and the spend limits, counting it towards them.
+// The returned func uncounts the item, for when it is not queued after all.
+func (w *Wallet) checkLimits(item MsgQueueItem) (func(), error) {
+	msgs := item.GetMsgs()
+	release := func() {}
+	if w.MsgRateLimiter != nil {
+		if burst := w.MsgRateLimiter.Burst(); len(msgs) > burst {
+			return nil, fmt.Errorf("%w: %d msgs with a burst of %d", ErrRateBurstExceeded, len(msgs), burst)
+		}
+		reservation := w.MsgRateLimiter.ReserveN(time.Now(), len(msgs))
+		if !reservation.OK() || reservation.Delay() > 0 {
+			reservation.Cancel()
+			return nil, ErrRateLimited
+		}
+		release = reservation.Cancel
+	}
+	if w.Spend == nil {
+		return release, nil
+	}
+
+	outflows, err := msgOutflows(msgs)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	reservedAt, err := w.Spend.reserve(outflows)
+	if err != nil {
+		release()
+		if _, ok := err.(*SpendLimitError); !ok {
+			w.logger().Error("unable to save spend ledger", F(FieldError, err))
+		}
+		return nil, err
+	}
+	return func() {
+		release()
+		if err := w.Spend.release(outflows, reservedAt); err != nil {
+			w.logger().Error("unable to save spend ledger", F(FieldError, err))
+		}
+	}, nil
+}
+
+// waitTxRateLimit waits until TxRateLimiter allows another tx to be sent, or until the wallet is stopped
+func (w *Wallet) waitTxRateLimit() {
+	if w.TxRateLimiter == nil {
+		return
+	}
+	reservation := w.TxRateLimiter.Reserve()
+	if !reservation.OK() || reservation.Delay() <= 0 {
+		return
+	}
+	timer := time.NewTimer(reservation.Delay())
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-w.StopChannel:
+	}
+}
@@ -0,0 +1,110 @@
+package wallet
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"golang.org/x/time/rate"
+)
+
+func swth(amount int64) sdktypes.Coins {
+	return sdktypes.NewCoins(sdktypes.NewInt64Coin("swth", amount))
+}
+
+func newSpendLimiter(t *testing.T, path string) *SpendLimiter {
+	t.Helper()
+	l, err := NewSpendLimiter([]SpendLimit{{Denom: "swth", Amount: sdkmath.NewInt(100), Window: time.Hour}}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestSpendLimiterReserve(t *testing.T) {
+	l := newSpendLimiter(t, "")
+	if _, err := l.reserve(swth(60)); err != nil {
+		t.Fatal(err)
+	}
+	_, err := l.reserve(swth(50))
+	if !errors.Is(err, ErrSpendLimitExceeded) {
+		t.Fatalf("expected ErrSpendLimitExceeded, got %v", err)
+	}
+	if spent := l.Spent("swth", time.Hour); !spent.Equal(sdkmath.NewInt(60)) {
+		t.Fatalf("expected 60 spent, got %s", spent)
+	}
+	if _, err := l.reserve(sdktypes.NewCoins(sdktypes.NewInt64Coin("other", 1000))); err != nil {
+		t.Fatalf("expected denom without a limit to be allowed, got %v", err)
+	}
+}
+
+func TestSpendLimiterReleaseFromReservedBucket(t *testing.T) {
+	l := newSpendLimiter(t, "")
+	reservedAt := time.Now().Add(-2 * spendBucketSize)
+	l.add(swth(60), reservedAt, false)
+
+	if err := l.release(swth(60), reservedAt); err != nil {
+		t.Fatal(err)
+	}
+	if spent := l.Spent("swth", time.Hour); !spent.IsZero() {
+		t.Fatalf("expected nothing spent after release, got %s", spent)
+	}
+}
+
+func TestSpendLimiterLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	l := newSpendLimiter(t, path)
+	if _, err := l.reserve(swth(60)); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := newSpendLimiter(t, path)
+	if spent := restarted.Spent("swth", time.Hour); !spent.Equal(sdkmath.NewInt(60)) {
+		t.Fatalf("expected 60 spent after restart, got %s", spent)
+	}
+}
+
+func TestSpendLimiterUndoesReservationThatFailsToSave(t *testing.T) {
+	l := newSpendLimiter(t, filepath.Join(t.TempDir(), "missing", "ledger.json"))
+	if _, err := l.reserve(swth(60)); err == nil {
+		t.Fatal("expected ledger save to fail")
+	}
+	if spent := l.Spent("swth", time.Hour); !spent.IsZero() {
+		t.Fatalf("expected nothing spent, got %s", spent)
+	}
+}
+
+func TestMsgOutflows(t *testing.T) {
+	send := &banktypes.MsgSend{Amount: swth(10)}
+	multiSend := &banktypes.MsgMultiSend{Inputs: []banktypes.Input{{Coins: swth(20)}}}
+	exec := authz.NewMsgExec(sdktypes.AccAddress("grantee"), []sdktypes.Msg{send})
+
+	outflows, err := msgOutflows([]sdktypes.Msg{send, multiSend, &exec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !outflows.Equal(swth(40)) {
+		t.Fatalf("expected 40swth of outflows, got %s", outflows)
+	}
+}
+
+func TestCheckLimitsRejectsGroupsOverBurst(t *testing.T) {
+	w := &Wallet{MsgRateLimiter: rate.NewLimiter(rate.Limit(1), 2)}
+	msgs := []sdktypes.Msg{&banktypes.MsgSend{}, &banktypes.MsgSend{}, &banktypes.MsgSend{}}
+
+	_, err := w.checkLimits(MsgQueueItem{Msgs: msgs})
+	if !errors.Is(err, ErrRateBurstExceeded) {
+		t.Fatalf("expected ErrRateBurstExceeded, got %v", err)
+	}
+	if _, err := w.checkLimits(MsgQueueItem{Msgs: msgs[:2]}); err != nil {
+		t.Fatalf("expected msgs within burst to be allowed, got %v", err)
+	}
+	if _, err := w.checkLimits(MsgQueueItem{Msgs: msgs[:1]}); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited once the burst is used, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package wallet
+
+import (
+	"context"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/sirupsen/logrus"
+)
+
+// Keys of the structured fields that the wallet logs with
+const (
+	FieldWallet   = "wallet"
+	FieldTxHash   = "tx_hash"
+	FieldSequence = "sequence"
+	FieldBatchID  = "batch_id"
+	FieldItemID   = "item_id"
+	FieldMsgs     = "msgs"
+	FieldError    = "error"
+)
+
+// Logger is a structured logger that the wallet logs through
+type Logger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+	// With returns a logger that logs fields with every entry
+	With(fields ...Field) Logger
+}
+
+// Field is a key and value logged with an entry
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F returns a field with key and value
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Redactor rewrites a field before it is logged, e.g. to hide sensitive values
+type Redactor func(field Field) Field
+
+// RedactMsgs is the default Redactor, which logs msgs by their type URLs instead of their payloads
+func RedactMsgs(field Field) Field {
+	switch value := field.Value.(type) {
+	case sdktypes.Msg:
+		field.Value = sdktypes.MsgTypeURL(value)
+	case []sdktypes.Msg:
+		typeURLs := make([]string, len(value))
+		for i, msg := range value {
+			typeURLs[i] = sdktypes.MsgTypeURL(msg)
+		}
+		field.Value = typeURLs
+	}
+	return field
+}
+
+// NewRedactingLogger returns a logger that passes every field through redact before logging it with logger
+func NewRedactingLogger(logger Logger, redact Redactor) Logger {
+	return &redactingLogger{logger: logger, redact: redact}
+}
+
+type redactingLogger struct {
+	logger Logger
+	redact Redactor
+}
+
+func (l *redactingLogger) Debug(msg string, fields ...Field) {
+	l.logger.Debug(msg, l.fields(fields)...)
+}
+func (l *redactingLogger) Info(msg string, fields ...Field) { l.logger.Info(msg, l.fields(fields)...) }
+func (l *redactingLogger) Warn(msg string, fields ...Field) { l.logger.Warn(msg, l.fields(fields)...) }
+func (l *redactingLogger) Error(msg string, fields ...Field) {
+	l.logger.Error(msg, l.fields(fields)...)
+}
+
+func (l *redactingLogger) With(fields ...Field) Logger {
+	return &redactingLogger{logger: l.logger.With(l.fields(fields)...), redact: l.redact}
+}
+
+func (l *redactingLogger) fields(fields []Field) []Field {
+	redacted := make([]Field, len(fields))
+	for i, field := range fields {
+		redacted[i] = l.redact(field)
+	}
+	return redacted
+}
+
+// NewNopLogger returns a logger that discards every entry
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...Field) {}
+func (nopLogger) Info(string, ...Field)  {}
+func (nopLogger) Warn(string, ...Field)  {}
+func (nopLogger) Error(string, ...Field) {}
+func (l nopLogger) With(...Field) Logger { return l }
+
+// DefaultLogger returns the logger used when none is set, which logs to the standard logrus logger
+// with msg payloads redacted
+func DefaultLogger() Logger {
+	return NewRedactingLogger(NewLogrusLogger(logrus.StandardLogger()), RedactMsgs)
+}
+
+// logger returns Logger if it is set, otherwise the default logger with the wallet label
+func (w *Wallet) logger() Logger {
+	if w.Logger != nil {
+		return w.Logger
+	}
+	return DefaultLogger().With(F(FieldWallet, w.metricsLabel()))
+}
+
+type batchIDKey struct{}
+
+// withBatchID returns ctx with the ID of the batch that is being sent
+func withBatchID(ctx context.Context, batchID string) context.Context {
+	return context.WithValue(ctx, batchIDKey{}, batchID)
+}
+
+// loggerFor returns the logger of the wallet with the batch ID of ctx if it has one
+func (w *Wallet) loggerFor(ctx context.Context) Logger {
+	if batchID, ok := ctx.Value(batchIDKey{}).(string); ok {
+		return w.logger().With(F(FieldBatchID, batchID))
+	}
+	return w.logger()
+}
+
+// loggerOrDefault returns logger if it is set, otherwise DefaultLogger
+func loggerOrDefault(logger Logger) Logger {
+	if logger != nil {
+		return logger
+	}
+	return DefaultLogger()
+}
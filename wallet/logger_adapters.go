@@ -0,0 +1,100 @@
+package wallet
+
+import (
+	"log/slog"
+
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+)
+
+// NewSlogLogger returns a Logger that logs with logger
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return slogLogger{logger: logger}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l slogLogger) Debug(msg string, fields ...Field) { l.logger.Debug(msg, slogArgs(fields)...) }
+func (l slogLogger) Info(msg string, fields ...Field)  { l.logger.Info(msg, slogArgs(fields)...) }
+func (l slogLogger) Warn(msg string, fields ...Field)  { l.logger.Warn(msg, slogArgs(fields)...) }
+func (l slogLogger) Error(msg string, fields ...Field) { l.logger.Error(msg, slogArgs(fields)...) }
+
+func (l slogLogger) With(fields ...Field) Logger {
+	return slogLogger{logger: l.logger.With(slogArgs(fields)...)}
+}
+
+func slogArgs(fields []Field) []any {
+	args := make([]any, len(fields))
+	for i, field := range fields {
+		args[i] = slog.Any(field.Key, field.Value)
+	}
+	return args
+}
+
+// NewLogrusLogger returns a Logger that logs with logger, e.g. logrus.StandardLogger()
+func NewLogrusLogger(logger logrus.FieldLogger) Logger {
+	return logrusLogger{logger: logger}
+}
+
+type logrusLogger struct {
+	logger logrus.FieldLogger
+}
+
+func (l logrusLogger) Debug(msg string, fields ...Field) { l.entry(fields).Debug(msg) }
+func (l logrusLogger) Info(msg string, fields ...Field)  { l.entry(fields).Info(msg) }
+func (l logrusLogger) Warn(msg string, fields ...Field)  { l.entry(fields).Warn(msg) }
+func (l logrusLogger) Error(msg string, fields ...Field) { l.entry(fields).Error(msg) }
+
+func (l logrusLogger) With(fields ...Field) Logger {
+	return logrusLogger{logger: l.entry(fields)}
+}
+
+func (l logrusLogger) entry(fields []Field) logrus.FieldLogger {
+	if len(fields) == 0 {
+		return l.logger
+	}
+	logrusFields := make(logrus.Fields, len(fields))
+	for _, field := range fields {
+		logrusFields[field.Key] = field.Value
+	}
+	return l.logger.WithFields(logrusFields)
+}
+
+// NewZerologLogger returns a Logger that logs with logger
+func NewZerologLogger(logger zerolog.Logger) Logger {
+	return zerologLogger{logger: logger}
+}
+
+type zerologLogger struct {
+	logger zerolog.Logger
+}
+
+func (l zerologLogger) Debug(msg string, fields ...Field) { zerologMsg(l.logger.Debug(), msg, fields) }
+func (l zerologLogger) Info(msg string, fields ...Field)  { zerologMsg(l.logger.Info(), msg, fields) }
+func (l zerologLogger) Warn(msg string, fields ...Field)  { zerologMsg(l.logger.Warn(), msg, fields) }
+func (l zerologLogger) Error(msg string, fields ...Field) { zerologMsg(l.logger.Error(), msg, fields) }
+
+func (l zerologLogger) With(fields ...Field) Logger {
+	ctx := l.logger.With()
+	for _, field := range fields {
+		if err, ok := field.Value.(error); ok {
+			ctx = ctx.AnErr(field.Key, err)
+			continue
+		}
+		ctx = ctx.Interface(field.Key, field.Value)
+	}
+	return zerologLogger{logger: ctx.Logger()}
+}
+
+func zerologMsg(event *zerolog.Event, msg string, fields []Field) {
+	for _, field := range fields {
+		if err, ok := field.Value.(error); ok {
+			event = event.AnErr(field.Key, err)
+			continue
+		}
+		event = event.Interface(field.Key, field.Value)
+	}
+	event.Msg(msg)
+}
@@ -0,0 +1,223 @@
+package wallet
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "carbon_wallet"
+
+// Metrics collects Prometheus metrics of the msg queue, broadcasts and confirmations of wallets.
+// A single Metrics can be shared by several wallets, which are told apart by the wallet label.
+type Metrics struct {
+	registerer prometheus.Registerer
+
+	batchSize        *prometheus.HistogramVec
+	signLatency      *prometheus.HistogramVec
+	broadcastLatency *prometheus.HistogramVec
+	confirmLatency   *prometheus.HistogramVec
+	broadcastErrors  *prometheus.CounterVec
+	feesSpent        *prometheus.CounterVec
+	accountSequence  *prometheus.GaugeVec
+	cachedHeightLag  *prometheus.GaugeVec
+
+	mu sync.Mutex
+	// gauges of the queue depth and pending txs of each wallet, by wallet label
+	walletGauges map[string][]prometheus.Collector
+}
+
+// NewMetrics returns wallet metrics registered with registerer, e.g. prometheus.DefaultRegisterer
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		registerer: registerer,
+		batchSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "batch_size_msgs",
+			Help:      "Number of msgs in each signed tx.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}, []string{"wallet"}),
+		signLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "sign_duration_seconds",
+			Help:      "Time taken to create and sign a tx.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 12),
+		}, []string{"wallet"}),
+		broadcastLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "broadcast_duration_seconds",
+			Help:      "Time taken to broadcast a tx until it is accepted or rejected by CheckTx.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+		}, []string{"wallet"}),
+		confirmLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "confirm_duration_seconds",
+			Help:      "Time from a tx being broadcasted until it is found committed.",
+			Buckets:   prometheus.ExponentialBuckets(0.25, 2, 10),
+		}, []string{"wallet"}),
+		broadcastErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "broadcast_errors_total",
+			Help:      "Number of txs rejected by CheckTx, by ABCI codespace and code.",
+		}, []string{"wallet", "codespace", "code"}),
+		feesSpent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "fees_spent_total",
+			Help:      "Fees paid by committed txs, by denom.",
+		}, []string{"wallet", "denom"}),
+		accountSequence: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "account_sequence",
+			Help:      "Account sequence that the next tx will be signed with.",
+		}, []string{"wallet"}),
+		cachedHeightLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "cached_height_lag_blocks",
+			Help:      "Number of blocks that the committed height of the last committed tx was ahead of the block height cached by the wallet for tx timeout heights.",
+		}, []string{"wallet"}),
+		walletGauges: make(map[string][]prometheus.Collector),
+	}
+	for _, collector := range []prometheus.Collector{
+		m.batchSize, m.signLatency, m.broadcastLatency, m.confirmLatency,
+		m.broadcastErrors, m.feesSpent, m.accountSequence, m.cachedHeightLag,
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// register registers the gauges that are read from w when scraped
+func (m *Metrics) register(w *Wallet) error {
+	label := w.metricsLabel()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.walletGauges[label]; ok {
+		return fmt.Errorf("wallet %s already has metrics registered", label)
+	}
+
+	labels := prometheus.Labels{"wallet": label}
+	queue, pendingTxs := w.MsgQueue, w.PendingTxs
+	gauges := []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   metricsNamespace,
+			Name:        "queue_depth_msgs",
+			Help:        "Number of items in the msg queue.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(queue.Len()) }),
+	}
+	if pendingTxs != nil {
+		gauges = append(gauges, prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   metricsNamespace,
+			Name:        "pending_txs",
+			Help:        "Number of broadcasted txs waiting to be confirmed.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(pendingTxs.Len()) }))
+	}
+	for i, gauge := range gauges {
+		if err := m.registerer.Register(gauge); err != nil {
+			for _, registered := range gauges[:i] {
+				m.registerer.Unregister(registered)
+			}
+			return err
+		}
+	}
+	m.walletGauges[label] = gauges
+	return nil
+}
+
+// unregister unregisters the gauges of w, and deletes its series from the other metrics
+func (m *Metrics) unregister(w *Wallet) {
+	label := w.metricsLabel()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, gauge := range m.walletGauges[label] {
+		m.registerer.Unregister(gauge)
+	}
+	delete(m.walletGauges, label)
+
+	labels := prometheus.Labels{"wallet": label}
+	m.batchSize.DeletePartialMatch(labels)
+	m.signLatency.DeletePartialMatch(labels)
+	m.broadcastLatency.DeletePartialMatch(labels)
+	m.confirmLatency.DeletePartialMatch(labels)
+	m.broadcastErrors.DeletePartialMatch(labels)
+	m.feesSpent.DeletePartialMatch(labels)
+	m.accountSequence.DeletePartialMatch(labels)
+	m.cachedHeightLag.DeletePartialMatch(labels)
+}
+
+// RegisterMetrics collects the metrics of the wallet with m, which may be shared with other wallets
+// as long as each has a different Label. Metrics are unregistered when the wallet is disconnected.
+func (w *Wallet) RegisterMetrics(m *Metrics) error {
+	if err := m.register(w); err != nil {
+		return err
+	}
+	w.Metrics = m
+	return nil
+}
+
+// metricsLabel returns the wallet label of the metrics of w, which is Label if set, otherwise the wallet address
+func (w *Wallet) metricsLabel() string {
+	if w.Label != "" {
+		return w.Label
+	}
+	return w.Bech32Addr
+}
+
+func (w *Wallet) unregisterMetrics() {
+	if w.Metrics == nil {
+		return
+	}
+	w.Metrics.unregister(w)
+}
+
+func (w *Wallet) observeSigned(msgCount int, start time.Time) {
+	if w.Metrics == nil {
+		return
+	}
+	label := w.metricsLabel()
+	w.Metrics.batchSize.WithLabelValues(label).Observe(float64(msgCount))
+	w.Metrics.signLatency.WithLabelValues(label).Observe(time.Since(start).Seconds())
+	w.Metrics.accountSequence.WithLabelValues(label).Set(float64(w.AccountSequence))
+}
+
+func (w *Wallet) observeBroadcast(response *sdktypes.TxResponse, start time.Time) {
+	if w.Metrics == nil {
+		return
+	}
+	label := w.metricsLabel()
+	w.Metrics.broadcastLatency.WithLabelValues(label).Observe(time.Since(start).Seconds())
+	if response != nil && response.Code != 0 {
+		w.Metrics.broadcastErrors.WithLabelValues(label, response.Codespace, strconv.FormatUint(uint64(response.Code), 10)).Inc()
+	}
+}
+
+func (w *Wallet) observeCommitted(txItems TxItems, response *sdktypes.TxResponse) {
+	if w.Metrics == nil {
+		return
+	}
+	label := w.metricsLabel()
+	if !txItems.CreatedAt.IsZero() {
+		w.Metrics.confirmLatency.WithLabelValues(label).Observe(time.Since(txItems.CreatedAt).Seconds())
+	}
+	for _, coin := range txItems.Fee {
+		amount, err := coin.Amount.ToLegacyDec().Float64()
+		if err != nil {
+			w.logger().Debug("unable to observe tx fee", F(FieldTxHash, response.TxHash), F(FieldError, err))
+			continue
+		}
+		w.Metrics.feesSpent.WithLabelValues(label, coin.Denom).Add(amount)
+	}
+	lag := response.Height - atomic.LoadInt64(&w.CurrentBlockHeight)
+	if lag < 0 {
+		lag = 0
+	}
+	w.Metrics.cachedHeightLag.WithLabelValues(label).Set(float64(lag))
+}
@@ -0,0 +1,75 @@
+package wallet
+
+import (
+	"sync"
+)
+
+// PendingTxs are the broadcasted txs that are waiting to be confirmed, by tx hash.
+// A tx is resolved by whichever of the confirmation methods removes it first.
+// A nil PendingTxs does not track any txs, and treats every tx as pending.
+type PendingTxs struct {
+	mu  sync.Mutex
+	txs map[string]TxItems
+}
+
+// NewPendingTxs returns an empty set of pending txs
+func NewPendingTxs() *PendingTxs {
+	return &PendingTxs{txs: make(map[string]TxItems)}
+}
+
+// Add adds txItems as pending
+func (p *PendingTxs) Add(txItems TxItems) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.txs[txItems.Hash] = txItems
+}
+
+// Has returns true if the tx with hash is pending
+func (p *PendingTxs) Has(hash string) bool {
+	if p == nil {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.txs[hash]
+	return ok
+}
+
+// Remove removes the tx with hash, returning false if it is not pending
+func (p *PendingTxs) Remove(hash string) (TxItems, bool) {
+	if p == nil {
+		return TxItems{}, true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	txItems, ok := p.txs[hash]
+	delete(p.txs, hash)
+	return txItems, ok
+}
+
+// Len returns the number of pending txs
+func (p *PendingTxs) Len() int {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.txs)
+}
+
+// List returns the pending txs
+func (p *PendingTxs) List() []TxItems {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	list := make([]TxItems, 0, len(p.txs))
+	for _, txItems := range p.txs {
+		list = append(list, txItems)
+	}
+	return list
+}
@@ -0,0 +1,54 @@
+package wallet
+
+import (
+	"sync"
+)
+
+// BroadcastPipeline broadcasts up to a max number of txs with consecutive sequences concurrently.
+// When any of them is rejected, signing is paused until the in-flight txs are done, so that the
+// account sequence can be resynced to the sequence the node expects next.
+type BroadcastPipeline struct {
+	slots    chan struct{}
+	inFlight sync.WaitGroup
+}
+
+// NewBroadcastPipeline returns a pipeline that broadcasts up to maxInFlight txs concurrently
+func NewBroadcastPipeline(maxInFlight int) *BroadcastPipeline {
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	return &BroadcastPipeline{slots: make(chan struct{}, maxInFlight)}
+}
+
+// acquire waits until there are less than the max number of txs in flight.
+// The next tx should only be signed after acquiring, and must then be dispatched or released.
+func (p *BroadcastPipeline) acquire() {
+	p.slots <- struct{}{}
+}
+
+// release releases an acquired slot without dispatching
+func (p *BroadcastPipeline) release() {
+	<-p.slots
+}
+
+// dispatch runs broadcast in a new goroutine, using the acquired slot until it is done.
+// Concurrent broadcasts may reach the node out of order, in which case the txs rejected with
+// a sequence mismatch are resynced and retried before the next tx is signed.
+func (p *BroadcastPipeline) dispatch(broadcast func()) {
+	p.inFlight.Add(1)
+	go func() {
+		defer p.inFlight.Done()
+		defer p.release()
+		broadcast()
+	}()
+}
+
+// Len returns the number of txs acquired or in flight
+func (p *BroadcastPipeline) Len() int {
+	return len(p.slots)
+}
+
+// wait waits for the in-flight txs to be done
+func (p *BroadcastPipeline) wait() {
+	p.inFlight.Wait()
+}
@@ -0,0 +1,298 @@
+package wallet
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Priority of a submitted msg. Msgs in a higher priority lane are sent first.
+type Priority int
+
+const (
+	// PriorityNormal is the default priority
+	PriorityNormal Priority = iota
+	// PriorityCritical is for urgent msgs such as cancels, which are sent before any other msgs
+	PriorityCritical
+	// PriorityBulk is for msgs that can wait until there are no other msgs to send
+	PriorityBulk
+	numPriorities
+)
+
+// priorityOrder is the order in which the lanes are drained
+var priorityOrder = []Priority{PriorityCritical, PriorityNormal, PriorityBulk}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityNormal:
+		return "normal"
+	case PriorityCritical:
+		return "critical"
+	case PriorityBulk:
+		return "bulk"
+	default:
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+}
+
+// OverflowPolicy decides what happens to msgs pushed to a full lane of the msg queue
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks until the lane has space
+	OverflowBlock OverflowPolicy = iota
+	// OverflowReject fails the msg with ErrQueueFull
+	OverflowReject
+	// OverflowDropOldestBulk drops the oldest bulk msg to make space, failing it with ErrQueueFull.
+	// Critical and normal lanes may then exceed their capacity by the number of bulk msgs dropped,
+	// so that the queue as a whole stays within the sum of its lane capacities.
+	// The msg is rejected if there are no bulk msgs to drop.
+	OverflowDropOldestBulk
+)
+
+type queueEntry struct {
+	item       MsgQueueItem
+	enqueuedAt time.Time
+	msgs       int
+	gas        uint64
+	bytes      int
+}
+
+// MsgQueue is a bounded msg queue with a FIFO lane for each priority.
+// Msgs are taken from the highest priority lane first, unless the oldest msg of a lower lane
+// has waited for longer than the starvation timeout, in which case that msg is taken first.
+type MsgQueue struct {
+	mu                sync.Mutex
+	notFull           *sync.Cond
+	lanes             [numPriorities][]queueEntry
+	capacities        [numPriorities]int
+	starvationTimeout time.Duration
+	overflowPolicy    OverflowPolicy
+	ready             chan struct{}
+	closed            bool
+	// estimated size of all queued items
+	msgs  int
+	gas   uint64
+	bytes int
+}
+
+// defaultLaneCapacity is the capacity of lanes without a configured capacity
+const defaultLaneCapacity = 1000
+
+// NewMsgQueue returns a msg queue with the given lane capacities. Lower priority lanes are
+// not starved for longer than starvationTimeout, set to 0 to always drain lanes strictly by priority.
+func NewMsgQueue(capacities map[Priority]int, starvationTimeout time.Duration, overflowPolicy OverflowPolicy) *MsgQueue {
+	q := &MsgQueue{
+		starvationTimeout: starvationTimeout,
+		overflowPolicy:    overflowPolicy,
+		ready:             make(chan struct{}, 1),
+	}
+	q.notFull = sync.NewCond(&q.mu)
+	for _, priority := range priorityOrder {
+		q.capacities[priority] = defaultLaneCapacity
+		if capacity := capacities[priority]; capacity > 0 {
+			q.capacities[priority] = capacity
+		}
+	}
+	return q
+}
+
+func (p Priority) valid() bool {
+	return p >= 0 && p < numPriorities
+}
+
+// Push adds item to the lane of its priority, handling a full lane according to the queue's
+// overflow policy. Returns the bulk item that was dropped to make space, if any.
+func (q *MsgQueue) Push(item MsgQueueItem) (dropped *MsgQueueItem, err error) {
+	return q.push(item, q.overflowPolicy == OverflowBlock)
+}
+
+// TryPush adds item to the lane of its priority like Push, but never blocks.
+// Fails with ErrQueueFull if the lane is full and the overflow policy is OverflowBlock.
+func (q *MsgQueue) TryPush(item MsgQueueItem) (dropped *MsgQueueItem, err error) {
+	return q.push(item, false)
+}
+
+func (q *MsgQueue) push(item MsgQueueItem, block bool) (dropped *MsgQueueItem, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, ErrWalletClosed
+	}
+	priority := item.lane()
+	for len(q.lanes[priority]) >= q.capacities[priority] {
+		if q.overflowPolicy == OverflowDropOldestBulk && len(q.lanes[PriorityBulk]) > 0 {
+			entry := q.remove(PriorityBulk)
+			dropped = &entry.item
+			break
+		}
+		if !block {
+			return nil, ErrQueueFull
+		}
+		q.notFull.Wait()
+		if q.closed {
+			return nil, ErrWalletClosed
+		}
+	}
+
+	entry := queueEntry{item: item, enqueuedAt: time.Now()}
+	entry.msgs, entry.gas, entry.bytes = itemSize(item)
+	q.lanes[priority] = append(q.lanes[priority], entry)
+	q.msgs += entry.msgs
+	q.gas += entry.gas
+	q.bytes += entry.bytes
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return dropped, nil
+}
+
+// Close stops the queue from accepting items, failing pushes with ErrWalletClosed.
+// The queued items can still be popped.
+func (q *MsgQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.notFull.Broadcast()
+}
+
+// Drain removes and returns all queued items
+func (q *MsgQueue) Drain() []MsgQueueItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items := []MsgQueueItem{}
+	for _, priority := range priorityOrder {
+		for len(q.lanes[priority]) > 0 {
+			items = append(items, q.remove(priority).item)
+		}
+	}
+	return items
+}
+
+// Ready returns a channel that receives after items are pushed to the queue.
+// Multiple pushes may be coalesced into a single receive.
+func (q *MsgQueue) Ready() <-chan struct{} {
+	return q.ready
+}
+
+// PopIf removes and returns the next item to be sent if accept returns true for it
+func (q *MsgQueue) PopIf(accept func(MsgQueueItem) bool) (MsgQueueItem, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	priority, ok := q.nextLane()
+	if !ok {
+		return MsgQueueItem{}, false
+	}
+	if !accept(q.lanes[priority][0].item) {
+		return MsgQueueItem{}, false
+	}
+	entry := q.remove(priority)
+	return entry.item, true
+}
+
+// remove removes the oldest entry of the lane with priority, the lane must not be empty
+func (q *MsgQueue) remove(priority Priority) queueEntry {
+	entry := q.lanes[priority][0]
+	q.lanes[priority][0] = queueEntry{}
+	q.lanes[priority] = q.lanes[priority][1:]
+	q.msgs -= entry.msgs
+	q.gas -= entry.gas
+	q.bytes -= entry.bytes
+	q.notFull.Broadcast()
+	return entry
+}
+
+// nextLane returns the lane to take the next item from
+func (q *MsgQueue) nextLane() (Priority, bool) {
+	if q.starvationTimeout > 0 {
+		// the highest priority lane cannot be starved, so only check the lower lanes,
+		// serving the lane with the oldest starved item first
+		starved, found := Priority(0), false
+		oldest := time.Now().Add(-q.starvationTimeout)
+		for _, priority := range priorityOrder[1:] {
+			lane := q.lanes[priority]
+			if len(lane) > 0 && lane[0].enqueuedAt.Before(oldest) {
+				starved, found, oldest = priority, true, lane[0].enqueuedAt
+			}
+		}
+		if found {
+			return starved, true
+		}
+	}
+	for _, priority := range priorityOrder {
+		if len(q.lanes[priority]) > 0 {
+			return priority, true
+		}
+	}
+	return 0, false
+}
+
+// Len returns the number of queued items
+func (q *MsgQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	length := 0
+	for _, lane := range q.lanes {
+		length += len(lane)
+	}
+	return length
+}
+
+// Size returns the number of msgs, the estimated gas and the estimated encoded bytes of all queued items
+func (q *MsgQueue) Size() (msgs int, gas uint64, bytes int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.msgs, q.gas, q.bytes
+}
+
+// OldestEnqueuedAt returns the time the oldest queued item was pushed, or false if the queue is empty
+func (q *MsgQueue) OldestEnqueuedAt() (time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	oldest, found := time.Time{}, false
+	for _, lane := range q.lanes {
+		if len(lane) > 0 && (!found || lane[0].enqueuedAt.Before(oldest)) {
+			oldest, found = lane[0].enqueuedAt, true
+		}
+	}
+	return oldest, found
+}
+
+// Capacity returns the total capacity of all lanes
+func (q *MsgQueue) Capacity() int {
+	capacity := 0
+	for _, laneCapacity := range q.capacities {
+		capacity += laneCapacity
+	}
+	return capacity
+}
+
+// FillRatio returns the number of queued items as a ratio of the total capacity of all lanes
+func (q *MsgQueue) FillRatio() float64 {
+	return float64(q.Len()) / float64(q.Capacity())
+}
+
+// LaneLen returns the number of queued items with priority
+func (q *MsgQueue) LaneLen(priority Priority) int {
+	if !priority.valid() {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.lanes[priority])
+}
+
+// lane returns the lane of the item, unknown priorities are queued as normal
+func (item MsgQueueItem) lane() Priority {
+	if !item.Priority.valid() {
+		return PriorityNormal
+	}
+	return item.Priority
+}
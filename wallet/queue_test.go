@@ -0,0 +1,164 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// priorityItem returns an item with id and priority
+func priorityItem(id string, priority Priority) MsgQueueItem {
+	return MsgQueueItem{ID: id, Msg: &banktypes.MsgSend{}, Priority: priority}
+}
+
+// popIDs pops every item of q, returning their IDs in the order they were popped
+func popIDs(q *MsgQueue) []string {
+	ids := []string{}
+	for {
+		item, ok := q.PopIf(func(MsgQueueItem) bool { return true })
+		if !ok {
+			return ids
+		}
+		ids = append(ids, item.ID)
+	}
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMsgQueuePopsByPriority(t *testing.T) {
+	q := NewMsgQueue(nil, 0, OverflowBlock)
+	for _, item := range []MsgQueueItem{
+		priorityItem("bulk1", PriorityBulk),
+		priorityItem("normal1", PriorityNormal),
+		priorityItem("critical1", PriorityCritical),
+		priorityItem("normal2", PriorityNormal),
+		priorityItem("unknown", Priority(42)),
+		priorityItem("critical2", PriorityCritical),
+	} {
+		if _, err := q.Push(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if q.LaneLen(PriorityNormal) != 3 {
+		t.Fatalf("expected unknown priority to be queued as normal, got %d normal items", q.LaneLen(PriorityNormal))
+	}
+
+	expected := []string{"critical1", "critical2", "normal1", "normal2", "unknown", "bulk1"}
+	if ids := popIDs(q); !equalIDs(ids, expected) {
+		t.Fatalf("expected %v, got %v", expected, ids)
+	}
+}
+
+func TestMsgQueueServesStarvedLanes(t *testing.T) {
+	q := NewMsgQueue(nil, time.Minute, OverflowBlock)
+	for _, item := range []MsgQueueItem{
+		priorityItem("bulk1", PriorityBulk),
+		priorityItem("normal1", PriorityNormal),
+		priorityItem("critical1", PriorityCritical),
+	} {
+		if _, err := q.Push(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the bulk item has waited for longer than the starvation timeout
+	q.lanes[PriorityBulk][0].enqueuedAt = time.Now().Add(-2 * time.Minute)
+
+	expected := []string{"bulk1", "critical1", "normal1"}
+	if ids := popIDs(q); !equalIDs(ids, expected) {
+		t.Fatalf("expected %v, got %v", expected, ids)
+	}
+}
+
+func TestMsgQueueOverflowReject(t *testing.T) {
+	q := NewMsgQueue(map[Priority]int{PriorityNormal: 1}, 0, OverflowReject)
+	if _, err := q.Push(priorityItem("a", PriorityNormal)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Push(priorityItem("b", PriorityNormal)); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if _, err := q.Push(priorityItem("c", PriorityCritical)); err != nil {
+		t.Fatalf("expected other lanes to have space, got %v", err)
+	}
+}
+
+func TestMsgQueueOverflowDropOldestBulk(t *testing.T) {
+	q := NewMsgQueue(map[Priority]int{PriorityNormal: 1, PriorityBulk: 2}, 0, OverflowDropOldestBulk)
+	for _, item := range []MsgQueueItem{
+		priorityItem("normal1", PriorityNormal),
+		priorityItem("bulk1", PriorityBulk),
+		priorityItem("bulk2", PriorityBulk),
+	} {
+		if _, err := q.Push(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dropped, err := q.Push(priorityItem("normal2", PriorityNormal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped == nil || dropped.ID != "bulk1" {
+		t.Fatalf("expected oldest bulk item to be dropped, got %+v", dropped)
+	}
+	if _, err := q.Push(priorityItem("normal3", PriorityNormal)); err != nil {
+		t.Fatal(err)
+	}
+	// there are no bulk items left to drop
+	if _, err := q.Push(priorityItem("normal4", PriorityNormal)); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if q.Len() > q.Capacity() {
+		t.Fatalf("expected queue to stay within its capacity of %d, got %d items", q.Capacity(), q.Len())
+	}
+}
+
+func TestMsgQueueTryPushNeverBlocks(t *testing.T) {
+	q := NewMsgQueue(map[Priority]int{PriorityNormal: 1}, 0, OverflowBlock)
+	if _, err := q.TryPush(priorityItem("a", PriorityNormal)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.TryPush(priorityItem("b", PriorityNormal)); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+}
+
+func TestMsgQueueOverflowBlock(t *testing.T) {
+	q := NewMsgQueue(map[Priority]int{PriorityNormal: 1}, 0, OverflowBlock)
+	if _, err := q.Push(priorityItem("a", PriorityNormal)); err != nil {
+		t.Fatal(err)
+	}
+
+	pushed := make(chan error, 1)
+	go func() {
+		_, err := q.Push(priorityItem("b", PriorityNormal))
+		pushed <- err
+	}()
+	select {
+	case err := <-pushed:
+		t.Fatalf("expected push to block while the lane is full, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	q.Close()
+	select {
+	case err := <-pushed:
+		if !errors.Is(err, ErrWalletClosed) {
+			t.Fatalf("expected ErrWalletClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected blocked push to return once the queue is closed")
+	}
+}
@@ -0,0 +1,68 @@
+package wallet
+
+import (
+	"github.com/Switcheo/carbon-wallet-go/api"
+	"github.com/cosmos/cosmos-sdk/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// rebroadcastTimedOut signs and broadcasts the items of a tx that timed out again, but only once the
+// tx is provably invalid, i.e. a block has been committed after its timeout height and it was not
+// committed, so that no msg is ever executed twice. Until then, the tx continues to be confirmed.
+// Items that have been broadcasted more than MaxRebroadcasts times are failed instead.
+func (w *Wallet) rebroadcastTimedOut(txItems TxItems) {
+	height, err := api.GetLatestBlockHeight(w.GRPCURL, w.ClientCtx)
+	if err != nil || !pastTimeoutHeight(height, txItems) {
+		w.requeueConfirmTransaction(txItems)
+		return
+	}
+	_, err = api.GetTx(w.GRPCURL, txItems.Hash, w.ClientCtx)
+	if status.Code(err) != codes.NotFound {
+		// the tx was committed, or it is unknown if it was
+		w.requeueConfirmTransaction(txItems)
+		return
+	}
+
+	w.expireTx(txItems)
+}
+
+// pastTimeoutHeight returns true if a block has been committed after the timeout height of txItems at height.
+// Txs are indexed after they are committed, so a tx committed at its timeout height may not be found
+// until the next block, and only then is a tx that is not found provably not committed.
+func pastTimeoutHeight(height int64, txItems TxItems) bool {
+	return txItems.TimeoutHeight > 0 && height > int64(txItems.TimeoutHeight)
+}
+
+// resendsExpired returns true if the items of txItems are resent once it expires without being committed,
+// rather than being failed once it times out. Expiry can only be proven for a tx with a timeout height.
+func (w *Wallet) resendsExpired(txItems TxItems) bool {
+	return w.RebroadcastTimedOutTxs && txItems.TimeoutHeight > 0
+}
+
+// expireTx resolves a pending tx that was not committed by its timeout height. Its items are resent
+// up to MaxRebroadcasts times if RebroadcastTimedOutTxs is set, otherwise they are failed.
+// The items of a tx that may never have been broadcasted are resent regardless of their attempts.
+func (w *Wallet) expireTx(txItems TxItems) {
+	if _, ok := w.removePending(txItems.Hash); !ok {
+		return
+	}
+	w.logger().Warn("tx expired without being committed", F(FieldTxHash, txItems.Hash), F("timeout_height", txItems.TimeoutHeight))
+	w.sequenceGap(txItems)
+
+	response := types.TxResponse{TxHash: txItems.Hash}
+	endConfirmSpan(txItems, &response, ErrTxTimedOut)
+	retry, failed := []MsgQueueItem{}, []MsgQueueItem{}
+	for _, item := range txItems.Items {
+		if !w.RebroadcastTimedOutTxs || (!txItems.Unbroadcast && item.Attempts > w.MaxRebroadcasts) {
+			failed = append(failed, item)
+			continue
+		}
+		retry = append(retry, item)
+	}
+	w.runCallback(&response, failed, ErrTxTimedOut)
+	if len(retry) > 0 {
+		w.logger().Warn("rebroadcasting msgs of expired tx", F(FieldTxHash, txItems.Hash), F("msg_count", len(retry)))
+		w.retryBatches(retriesAfterBroadcast(retry))
+	}
+}
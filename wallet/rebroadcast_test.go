@@ -0,0 +1,136 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// expiredTx returns a pending tx of w with an item for each number of attempts,
+// recording the error that each item is failed with in failed
+func expiredTx(w *Wallet, unbroadcast bool, failed map[string]error, attempts ...int) TxItems {
+	txItems := TxItems{Hash: "TX1", TimeoutHeight: 100, Unbroadcast: unbroadcast}
+	for i, n := range attempts {
+		id := string(rune('a' + i))
+		txItems.Items = append(txItems.Items, MsgQueueItem{
+			ID:       id,
+			Msg:      &banktypes.MsgSend{},
+			Async:    true,
+			Attempts: n,
+			Callback: func(_ *sdktypes.TxResponse, _ sdktypes.Msg, err error) {
+				failed[id] = err
+			},
+		})
+	}
+	w.PendingTxs.Add(txItems)
+	return txItems
+}
+
+// retriedIDs returns the IDs of the items retried by w
+func retriedIDs(t *testing.T, w *Wallet) []string {
+	t.Helper()
+	ids := []string{}
+	for len(w.RetryBatchQueue) > 0 {
+		for _, item := range <-w.RetryBatchQueue {
+			if !item.Async {
+				t.Fatalf("expected retried item %s to be async", item.ID)
+			}
+			ids = append(ids, item.ID)
+		}
+	}
+	return ids
+}
+
+func newExpiryWallet(rebroadcast bool) *Wallet {
+	return &Wallet{
+		PendingTxs:             NewPendingTxs(),
+		RetryBatchQueue:        make(chan []MsgQueueItem, 4),
+		StopChannel:            make(chan int, 3),
+		Sequences:              NewSequenceManager(),
+		MaxSequenceRetries:     3,
+		RebroadcastTimedOutTxs: rebroadcast,
+		MaxRebroadcasts:        1,
+	}
+}
+
+func TestExpireTxFailsItemsWithoutRebroadcast(t *testing.T) {
+	for _, unbroadcast := range []bool{false, true} {
+		w := newExpiryWallet(false)
+		failed := map[string]error{}
+		txItems := expiredTx(w, unbroadcast, failed, 0)
+		if w.resendsExpired(txItems) {
+			t.Fatal("expected expired tx not to be resent")
+		}
+		w.expireTx(txItems)
+
+		if !errors.Is(failed["a"], ErrTxTimedOut) {
+			t.Fatalf("expected item to fail with ErrTxTimedOut, got %v", failed["a"])
+		}
+		if ids := retriedIDs(t, w); len(ids) != 0 {
+			t.Fatalf("expected no retried items, got %v", ids)
+		}
+		if w.PendingTxs.Len() != 0 {
+			t.Fatal("expected tx to no longer be pending")
+		}
+	}
+}
+
+func TestExpireTxRebroadcastsUpToMaxRebroadcasts(t *testing.T) {
+	w := newExpiryWallet(true)
+	failed := map[string]error{}
+	txItems := expiredTx(w, false, failed, 1, 2)
+	if !w.resendsExpired(txItems) {
+		t.Fatal("expected expired tx to be resent")
+	}
+	w.expireTx(txItems)
+
+	if ids := retriedIDs(t, w); len(ids) != 1 || ids[0] != "a" {
+		t.Fatalf("expected item a to be retried, got %v", ids)
+	}
+	if _, ok := failed["a"]; ok {
+		t.Fatal("expected item a not to be failed")
+	}
+	if !errors.Is(failed["b"], ErrTxTimedOut) {
+		t.Fatalf("expected item b to fail with ErrTxTimedOut, got %v", failed["b"])
+	}
+}
+
+func TestExpireTxRebroadcastsUnbroadcastItems(t *testing.T) {
+	w := newExpiryWallet(true)
+	failed := map[string]error{}
+	w.expireTx(expiredTx(w, true, failed, 5))
+
+	if ids := retriedIDs(t, w); len(ids) != 1 || ids[0] != "a" {
+		t.Fatalf("expected item a to be retried, got %v", ids)
+	}
+	if len(failed) != 0 {
+		t.Fatalf("expected no failed items, got %v", failed)
+	}
+}
+
+func TestResendsExpiredRequiresTimeoutHeight(t *testing.T) {
+	w := newExpiryWallet(true)
+	if w.resendsExpired(TxItems{Hash: "TX1"}) {
+		t.Fatal("expected tx without timeout height not to be resent")
+	}
+}
+
+func TestPastTimeoutHeight(t *testing.T) {
+	cases := []struct {
+		height        int64
+		timeoutHeight uint64
+		past          bool
+	}{
+		{height: 99, timeoutHeight: 100, past: false},
+		{height: 100, timeoutHeight: 100, past: false},
+		{height: 101, timeoutHeight: 100, past: true},
+		{height: 101, timeoutHeight: 0, past: false},
+	}
+	for _, c := range cases {
+		if past := pastTimeoutHeight(c.height, TxItems{TimeoutHeight: c.timeoutHeight}); past != c.past {
+			t.Errorf("pastTimeoutHeight(%d, %d) = %v, expected %v", c.height, c.timeoutHeight, past, c.past)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+// ErrorClass is a class of tx errors that are remediated the same way
+type ErrorClass int
+
+const (
+	// ErrorClassUnknown is any error without a remediation, which fails the msgs right away
+	ErrorClassUnknown ErrorClass = iota
+	// ErrorClassSequenceMismatch is retried with the resynced account sequence
+	ErrorClassSequenceMismatch
+	// ErrorClassOutOfGas is retried with a higher gas limit
+	ErrorClassOutOfGas
+	// ErrorClassInsufficientFee is retried with a higher gas price
+	ErrorClassInsufficientFee
+	// ErrorClassTxInMempool is a tx that is already in the mempool, which is tracked as broadcasted
+	ErrorClassTxInMempool
+	// ErrorClassMempoolFull is retried after backing off
+	ErrorClassMempoolFull
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassUnknown:
+		return "unknown"
+	case ErrorClassSequenceMismatch:
+		return "sequence mismatch"
+	case ErrorClassOutOfGas:
+		return "out of gas"
+	case ErrorClassInsufficientFee:
+		return "insufficient fee"
+	case ErrorClassTxInMempool:
+		return "tx in mempool"
+	case ErrorClassMempoolFull:
+		return "mempool full"
+	default:
+		return fmt.Sprintf("error class(%d)", int(c))
+	}
+}
+
+// ClassifyError returns the class of the error that the tx with response failed with
+func ClassifyError(response *sdktypes.TxResponse) ErrorClass {
+	switch {
+	case responseIs(response, ErrSequenceMismatch):
+		return ErrorClassSequenceMismatch
+	case responseIs(response, ErrOutOfGas):
+		return ErrorClassOutOfGas
+	case responseIs(response, ErrInsufficientFee):
+		return ErrorClassInsufficientFee
+	case responseIs(response, ErrTxInMempoolCache):
+		return ErrorClassTxInMempool
+	case responseIs(response, ErrMempoolFull):
+		return ErrorClassMempoolFull
+	default:
+		return ErrorClassUnknown
+	}
+}
+
+// RemediationPolicy is how the msgs of a tx that failed with an error class are retried
+type RemediationPolicy struct {
+	// MaxRetries is the max number of times the msgs are retried, before they are failed.
+	// Sequence mismatches are limited by Wallet.MaxSequenceRetries instead.
+	MaxRetries int
+	// Multiplier is applied to the gas limit of out of gas retries,
+	// and to the gas price of insufficient fee retries, on each retry
+	Multiplier float64
+	// Backoff is the wait before each retry
+	Backoff BackoffPolicy
+}
+
+// DefaultRemediationPolicies returns the default remediation policies. Error classes without a policy are not retried.
+func DefaultRemediationPolicies() map[ErrorClass]RemediationPolicy {
+	return map[ErrorClass]RemediationPolicy{
+		ErrorClassOutOfGas:        {MaxRetries: 2, Multiplier: 1.5},
+		ErrorClassInsufficientFee: {MaxRetries: 2, Multiplier: 1.5},
+		ErrorClassMempoolFull: {MaxRetries: 5, Backoff: BackoffPolicy{
+			Base:       time.Second,
+			Multiplier: 2,
+			Max:        30 * time.Second,
+			Jitter:     0.2,
+		}},
+	}
+}
+
+// Remediation is how the msgs of a failed tx are handled
+type Remediation struct {
+	Class ErrorClass
+	// Retry retries the msgs, otherwise they are failed
+	Retry bool
+	// GasMultiplier is applied to the gas limit of the retry
+	GasMultiplier float64
+	// FeeMultiplier is applied to the gas price of the retry
+	FeeMultiplier float64
+	// Delay is the wait before the retry
+	Delay time.Duration
+}
+
+// RemediationHook is called with the remediation decided by the policy for the items of a tx that
+// failed with response, and returns the remediation to apply instead. Committed is true if the tx
+// failed when it was committed, rather than being rejected by CheckTx.
+type RemediationHook func(remediation Remediation, items []MsgQueueItem, response *sdktypes.TxResponse, committed bool) Remediation
+
+// remediation returns the remediation of items that failed with response, following the policy of its error class
+func (w *Wallet) remediation(items []MsgQueueItem, response *sdktypes.TxResponse, committed bool) Remediation {
+	remediation := Remediation{Class: ClassifyError(response), GasMultiplier: 1, FeeMultiplier: 1}
+	attempts := 0
+	for _, item := range items {
+		if item.Attempts > attempts {
+			attempts = item.Attempts
+		}
+	}
+
+	switch remediation.Class {
+	case ErrorClassSequenceMismatch:
+		remediation.Retry = !committed && w.Sequences != nil
+	case ErrorClassOutOfGas, ErrorClassInsufficientFee, ErrorClassMempoolFull:
+		policy, ok := w.RemediationPolicies[remediation.Class]
+		if !ok || attempts > policy.MaxRetries {
+			break
+		}
+		remediation.Retry = true
+		if policy.Backoff.Base > 0 && attempts > 0 {
+			remediation.Delay = policy.Backoff.Interval(uint(attempts - 1))
+		}
+		if policy.Multiplier > 1 {
+			switch remediation.Class {
+			case ErrorClassOutOfGas:
+				remediation.GasMultiplier = policy.Multiplier
+			case ErrorClassInsufficientFee:
+				remediation.FeeMultiplier = policy.Multiplier
+			}
+		}
+	}
+
+	if w.RemediationHook != nil {
+		remediation = w.RemediationHook(remediation, items, response, committed)
+	}
+	return remediation
+}
+
+// remediateRejected handles the items of tx that was rejected by CheckTx with response and err.
+// Returns false if the items are to be failed with err.
+func (w *Wallet) remediateRejected(ctx context.Context, tx authsigning.Tx, items []MsgQueueItem, response *sdktypes.TxResponse, err error) bool {
+	if ClassifyError(response) == ErrorClassTxInMempool {
+		// the same tx was broadcasted already, so it is confirmed like any other broadcasted tx
+		w.loggerFor(ctx).Warn("tx is already in the mempool, tracking it as broadcasted", F(FieldTxHash, response.TxHash))
+		w.trackTx(ctx, tx, response, items)
+		for _, item := range items {
+			w.EnqueueMsgResponse(item, response, nil)
+		}
+		return true
+	}
+
+	remediation := w.remediation(items, response, false)
+	if !remediation.Retry {
+		return false
+	}
+	w.loggerFor(ctx).Warn("retrying rejected msgs", F("msg_count", len(items)), F("error_class", remediation.Class.String()))
+	if remediation.Class == ErrorClassSequenceMismatch {
+		w.retrySequenceMismatch(items, response, err)
+		return true
+	}
+	w.retryRemediated(items, remediation, false)
+	return true
+}
+
+// remediateCommitted handles the items of a tx that failed with response when it was committed.
+// Returns false if the items are to be failed.
+func (w *Wallet) remediateCommitted(txItems TxItems, response *sdktypes.TxResponse) bool {
+	remediation := w.remediation(txItems.Items, response, true)
+	if !remediation.Retry {
+		return false
+	}
+	w.logger().Warn("retrying msgs of failed tx", F(FieldTxHash, response.TxHash), F("msg_count", len(txItems.Items)), F("error_class", remediation.Class.String()))
+
+	w.retryRemediated(retriesAfterBroadcast(txItems.Items), remediation, true)
+	return true
+}
+
+// retryRemediated resends items as a single tx following remediation. Unless queued is true,
+// must only be called from the goroutine processing the msg queue.
+func (w *Wallet) retryRemediated(items []MsgQueueItem, remediation Remediation, queued bool) {
+	for i := range items {
+		items[i].GasMultiplier = multiplier(items[i].GasMultiplier) * multiplier(remediation.GasMultiplier)
+		items[i].FeeMultiplier = multiplier(items[i].FeeMultiplier) * multiplier(remediation.FeeMultiplier)
+	}
+	if remediation.Delay <= 0 && !queued {
+		w.resubmit(items)
+		return
+	}
+	if remediation.Delay <= 0 {
+		w.retryBatches(items)
+		return
+	}
+	if w.RetryBacklog != nil {
+		w.retryDelayed(items, remediation.Delay)
+		return
+	}
+	retry := func() { w.retryBatches(items) }
+	if w.Scheduler != nil {
+		w.Scheduler.After(remediation.Delay, func() { go retry() })
+		return
+	}
+	time.AfterFunc(remediation.Delay, retry)
+}
+
+// multiplier returns m, or 1 if it is unset
+func multiplier(m float64) float64 {
+	if m <= 0 {
+		return 1
+	}
+	return m
+}
+
+// retryDelayed requeues items after delay, holding them in RetryBacklog until then,
+// so that they keep the wallet from settling, and are failed if it is stopped first
+func (w *Wallet) retryDelayed(items []MsgQueueItem, delay time.Duration) {
+	id, ok := w.RetryBacklog.hold(items)
+	if !ok {
+		w.failBatch(items, ErrWalletClosed)
+		return
+	}
+	// releasing never blocks, so it can be run on the scheduler
+	release := func() { w.RetryBacklog.release(w.RetryBatchQueue, id) }
+	if w.Scheduler != nil {
+		w.Scheduler.After(delay, release)
+		return
+	}
+	time.AfterFunc(delay, release)
+}
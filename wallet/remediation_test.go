@@ -0,0 +1,122 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+// failedResponse returns the response of a tx that failed with err
+func failedResponse(err abciError) *sdktypes.TxResponse {
+	return &sdktypes.TxResponse{TxHash: "TX1", Codespace: err.Codespace(), Code: err.ABCICode()}
+}
+
+// attemptedItems returns an item that was attempted attempts times
+func attemptedItems(attempts int) []MsgQueueItem {
+	return []MsgQueueItem{{ID: "a", Async: true, Attempts: attempts}}
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		response *sdktypes.TxResponse
+		class    ErrorClass
+	}{
+		{response: failedResponse(ErrSequenceMismatch), class: ErrorClassSequenceMismatch},
+		{response: failedResponse(ErrOutOfGas), class: ErrorClassOutOfGas},
+		{response: failedResponse(ErrInsufficientFee), class: ErrorClassInsufficientFee},
+		{response: failedResponse(ErrTxInMempoolCache), class: ErrorClassTxInMempool},
+		{response: failedResponse(ErrMempoolFull), class: ErrorClassMempoolFull},
+		{response: failedResponse(ErrInsufficientFunds), class: ErrorClassUnknown},
+		{response: &sdktypes.TxResponse{TxHash: "TX1"}, class: ErrorClassUnknown},
+		{response: nil, class: ErrorClassUnknown},
+	}
+	for _, c := range cases {
+		if class := ClassifyError(c.response); class != c.class {
+			t.Errorf("expected %s for %+v, got %s", c.class, c.response, class)
+		}
+	}
+}
+
+func TestRemediation(t *testing.T) {
+	w := &Wallet{RemediationPolicies: DefaultRemediationPolicies(), Sequences: NewSequenceManager()}
+	cases := []struct {
+		name      string
+		err       abciError
+		attempts  int
+		committed bool
+		expected  Remediation
+	}{
+		{name: "out of gas", err: ErrOutOfGas, attempts: 1, expected: Remediation{Class: ErrorClassOutOfGas, Retry: true, GasMultiplier: 1.5, FeeMultiplier: 1}},
+		{name: "out of gas retries exhausted", err: ErrOutOfGas, attempts: 3, expected: Remediation{Class: ErrorClassOutOfGas, GasMultiplier: 1, FeeMultiplier: 1}},
+		{name: "insufficient fee", err: ErrInsufficientFee, attempts: 1, expected: Remediation{Class: ErrorClassInsufficientFee, Retry: true, GasMultiplier: 1, FeeMultiplier: 1.5}},
+		{name: "mempool full", err: ErrMempoolFull, attempts: 2, expected: Remediation{Class: ErrorClassMempoolFull, Retry: true, GasMultiplier: 1, FeeMultiplier: 1}},
+		{name: "rejected sequence mismatch", err: ErrSequenceMismatch, attempts: 1, expected: Remediation{Class: ErrorClassSequenceMismatch, Retry: true, GasMultiplier: 1, FeeMultiplier: 1}},
+		{name: "committed sequence mismatch", err: ErrSequenceMismatch, attempts: 1, committed: true, expected: Remediation{Class: ErrorClassSequenceMismatch, GasMultiplier: 1, FeeMultiplier: 1}},
+		{name: "unknown", err: ErrInsufficientFunds, attempts: 1, expected: Remediation{Class: ErrorClassUnknown, GasMultiplier: 1, FeeMultiplier: 1}},
+	}
+	for _, c := range cases {
+		remediation := w.remediation(attemptedItems(c.attempts), failedResponse(c.err), c.committed)
+		// the delay is jittered, so it is only checked to be set
+		if c.expected.Class == ErrorClassMempoolFull {
+			if remediation.Delay <= 0 {
+				t.Errorf("%s: expected a delay, got %s", c.name, remediation.Delay)
+			}
+			remediation.Delay = 0
+		}
+		if remediation != c.expected {
+			t.Errorf("%s: expected %+v, got %+v", c.name, c.expected, remediation)
+		}
+	}
+}
+
+func TestRemediationWithoutPolicy(t *testing.T) {
+	w := &Wallet{}
+	if remediation := w.remediation(attemptedItems(1), failedResponse(ErrOutOfGas), false); remediation.Retry {
+		t.Fatal("expected error class without a policy not to be retried")
+	}
+}
+
+func TestRemediationHook(t *testing.T) {
+	w := &Wallet{
+		RemediationHook: func(remediation Remediation, items []MsgQueueItem, response *sdktypes.TxResponse, committed bool) Remediation {
+			remediation.Retry = true
+			remediation.Delay = time.Second
+			return remediation
+		},
+	}
+	remediation := w.remediation(attemptedItems(1), failedResponse(ErrInsufficientFunds), true)
+	if !remediation.Retry || remediation.Delay != time.Second {
+		t.Fatalf("expected remediation of the hook, got %+v", remediation)
+	}
+}
+
+func TestRemediateCommittedRetriesAfterDelay(t *testing.T) {
+	w := &Wallet{
+		RemediationPolicies: map[ErrorClass]RemediationPolicy{
+			ErrorClassMempoolFull: {MaxRetries: 1, Backoff: BackoffPolicy{Base: 20 * time.Millisecond}},
+		},
+		RetryBatchQueue: make(chan []MsgQueueItem, 1),
+		RetryBacklog:    NewRetryBacklog(),
+		StopChannel:     make(chan int),
+	}
+	items := []MsgQueueItem{{ID: "a", Attempts: 1}}
+	if !w.remediateCommitted(TxItems{Hash: "TX1", Items: items}, failedResponse(ErrMempoolFull)) {
+		t.Fatal("expected msgs to be retried")
+	}
+	if w.RetryBacklog.Len() != 1 {
+		t.Fatal("expected retry to be held until its delay")
+	}
+
+	select {
+	case batch := <-w.RetryBatchQueue:
+		if len(batch) != 1 || batch[0].ID != "a" || !batch[0].Async {
+			t.Fatalf("expected item a to be retried as async, got %+v", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected retry to be released after its delay")
+	}
+	if w.RetryBacklog.Len() != 0 {
+		t.Fatal("expected released retry to leave the backlog")
+	}
+}
@@ -0,0 +1,161 @@
+package wallet
+
+import (
+	"sync"
+)
+
+// RetryBacklog holds the retried batches that do not fit in RetryBatchQueue, so that requeueing
+// a retry never blocks, e.g. while a broadcast that holds a pipeline slot is requeueing its msgs.
+// The batches are moved to RetryBatchQueue as the goroutine processing the msg queue takes retries from it.
+// It also holds the batches waiting for a remediation delay, so that they are failed on shutdown.
+type RetryBacklog struct {
+	mu       sync.Mutex
+	overflow [][]MsgQueueItem
+	// batches waiting for a remediation delay, by hold ID
+	delayed map[uint64][]MsgQueueItem
+	nextID  uint64
+	closed  bool
+}
+
+// NewRetryBacklog returns an empty retry backlog
+func NewRetryBacklog() *RetryBacklog {
+	return &RetryBacklog{delayed: make(map[uint64][]MsgQueueItem)}
+}
+
+// Len returns the number of batches in the backlog, including the delayed ones
+func (b *RetryBacklog) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.overflow) + len(b.delayed)
+}
+
+// push sends batch to queue, or adds it to the backlog if queue is full.
+// Returns false once the backlog is closed.
+func (b *RetryBacklog) push(queue chan<- []MsgQueueItem, batch []MsgQueueItem) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return false
+	}
+	b.pushLocked(queue, batch)
+	return true
+}
+
+// pushLocked is push with the lock held. Batches are kept in order, so batch is added
+// to the backlog while it has other batches.
+func (b *RetryBacklog) pushLocked(queue chan<- []MsgQueueItem, batch []MsgQueueItem) {
+	if len(b.overflow) == 0 {
+		select {
+		case queue <- batch:
+			return
+		default:
+		}
+	}
+	b.overflow = append(b.overflow, batch)
+}
+
+// hold holds batch until it is released after its delay, returning its hold ID.
+// Returns false once the backlog is closed.
+func (b *RetryBacklog) hold(batch []MsgQueueItem) (uint64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return 0, false
+	}
+	b.nextID++
+	b.delayed[b.nextID] = batch
+	return b.nextID, true
+}
+
+// release pushes the batch held with id to queue, unless it was taken when the backlog was closed
+func (b *RetryBacklog) release(queue chan<- []MsgQueueItem, id uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch, ok := b.delayed[id]
+	if !ok {
+		return
+	}
+	delete(b.delayed, id)
+	b.pushLocked(queue, batch)
+}
+
+// refill moves the batches in the backlog to queue until it is full. Must be called after each
+// batch taken from queue, so that the backlog only has batches while queue does too.
+func (b *RetryBacklog) refill(queue chan<- []MsgQueueItem) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.overflow) > 0 {
+		select {
+		case queue <- b.overflow[0]:
+			b.overflow[0] = nil
+			b.overflow = b.overflow[1:]
+		default:
+			return
+		}
+	}
+}
+
+// close closes the backlog, returning the batches left in it, including the delayed ones
+func (b *RetryBacklog) close() [][]MsgQueueItem {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	batches := b.overflow
+	for id, batch := range b.delayed {
+		batches = append(batches, batch)
+		delete(b.delayed, id)
+	}
+	b.overflow = nil
+	return batches
+}
+
+// retryBatches requeues each non-empty batch to be sent as a separate tx, without blocking if
+// RetryBacklog is set. Batches are failed with ErrWalletClosed instead once the wallet is stopped.
+func (w *Wallet) retryBatches(batches ...[]MsgQueueItem) {
+	for _, b := range batches {
+		if len(b) == 0 {
+			continue
+		}
+		if w.RetryBacklog != nil {
+			if !w.RetryBacklog.push(w.RetryBatchQueue, b) {
+				w.failBatch(b, ErrWalletClosed)
+			}
+			continue
+		}
+		select {
+		case w.RetryBatchQueue <- b:
+		case <-w.StopChannel:
+			w.failBatch(b, ErrWalletClosed)
+		}
+	}
+}
+
+// nextRetryBatch takes the next retried batch from RetryBatchQueue without waiting, refilling it from RetryBacklog
+func (w *Wallet) nextRetryBatch() ([]MsgQueueItem, bool) {
+	select {
+	case items := <-w.RetryBatchQueue:
+		w.refillRetries()
+		return items, true
+	default:
+		return nil, false
+	}
+}
+
+// refillRetries moves the batches in RetryBacklog to RetryBatchQueue, after a batch was taken from it
+func (w *Wallet) refillRetries() {
+	if w.RetryBacklog != nil {
+		w.RetryBacklog.refill(w.RetryBatchQueue)
+	}
+}
+
+// failBatch responds to each item of b with err
+func (w *Wallet) failBatch(b []MsgQueueItem, err error) {
+	for _, item := range b {
+		w.EnqueueMsgResponse(item, nil, err)
+	}
+}
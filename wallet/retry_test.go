@@ -0,0 +1,66 @@
+package wallet
+
+import (
+	"testing"
+)
+
+func TestRetryBacklogKeepsOrder(t *testing.T) {
+	b := NewRetryBacklog()
+	queue := make(chan []MsgQueueItem, 1)
+	for _, id := range []string{"a", "b", "c"} {
+		if !b.push(queue, []MsgQueueItem{{ID: id}}) {
+			t.Fatal("expected batch to be pushed")
+		}
+	}
+	if b.Len() != 2 {
+		t.Fatalf("expected 2 batches in backlog, got %d", b.Len())
+	}
+
+	ids := []string{}
+	for len(ids) < 3 {
+		batch := <-queue
+		b.refill(queue)
+		ids = append(ids, batch[0].ID)
+	}
+	if ids[0] != "a" || ids[1] != "b" || ids[2] != "c" {
+		t.Fatalf("expected batches in order, got %v", ids)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("expected empty backlog, got %d", b.Len())
+	}
+}
+
+func TestRetryBacklogHold(t *testing.T) {
+	b := NewRetryBacklog()
+	queue := make(chan []MsgQueueItem, 1)
+	id, ok := b.hold([]MsgQueueItem{{ID: "a"}})
+	if !ok || b.Len() != 1 {
+		t.Fatal("expected batch to be held")
+	}
+	b.release(queue, id)
+	if b.Len() != 0 || len(queue) != 1 {
+		t.Fatal("expected batch to be released to queue")
+	}
+}
+
+func TestRetryBacklogClose(t *testing.T) {
+	b := NewRetryBacklog()
+	queue := make(chan []MsgQueueItem)
+	b.push(queue, []MsgQueueItem{{ID: "a"}})
+	id, _ := b.hold([]MsgQueueItem{{ID: "b"}})
+
+	if left := b.close(); len(left) != 2 {
+		t.Fatalf("expected 2 batches left, got %d", len(left))
+	}
+	if b.push(queue, []MsgQueueItem{{ID: "c"}}) {
+		t.Fatal("expected push to fail once closed")
+	}
+	if _, ok := b.hold([]MsgQueueItem{{ID: "d"}}); ok {
+		t.Fatal("expected hold to fail once closed")
+	}
+	// releasing a batch that was taken on close does nothing
+	b.release(queue, id)
+	if b.Len() != 0 {
+		t.Fatalf("expected empty backlog, got %d", b.Len())
+	}
+}
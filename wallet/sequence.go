@@ -0,0 +1,222 @@
+package wallet
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/Switcheo/carbon-wallet-go/api"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+// expectedSequenceRegex matches the sequence expected by the node in the raw_log of an
+// "account sequence mismatch, expected 5, got 7: incorrect account sequence" error
+var expectedSequenceRegex = regexp.MustCompile(`expected (\d+)`)
+
+// SequenceManager tracks what is known about the account sequence that the node expects next,
+// from the txs accepted and rejected by CheckTx and the txs that were never confirmed.
+// When a tx is rejected or a sequence gap is detected, the wallet resyncs its account sequence
+// before signing the next tx.
+type SequenceManager struct {
+	mu sync.Mutex
+	// the account sequence must be resynced before signing the next tx
+	outOfSync bool
+	// the account sequence must be fetched from the chain, as what the node expects is unknown
+	fetch bool
+	// highest sequence known to be expected next by the node
+	next      uint64
+	nextKnown bool
+	// lowest sequence of the txs rejected since the last resync
+	lowestRejected uint64
+	rejected       bool
+}
+
+// NewSequenceManager returns a sequence manager
+func NewSequenceManager() *SequenceManager {
+	return &SequenceManager{}
+}
+
+// Accepted records that the tx signed with sequence was accepted by CheckTx
+func (m *SequenceManager) Accepted(sequence uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.raiseNext(sequence + 1)
+}
+
+// Rejected records that the tx signed with sequence was rejected by CheckTx with response
+func (m *SequenceManager) Rejected(sequence uint64, response *sdktypes.TxResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if responseIs(response, ErrTxInMempoolCache) { // its sequence was used
+		m.raiseNext(sequence + 1)
+		return
+	}
+
+	if !m.rejected || sequence < m.lowestRejected {
+		m.lowestRejected = sequence
+	}
+	m.rejected = true
+	m.outOfSync = true
+
+	if responseIs(response, ErrSequenceMismatch) {
+		expected, ok := parseExpectedSequence(response.RawLog)
+		if !ok {
+			m.fetch = true
+			return
+		}
+		m.raiseNext(expected)
+	}
+}
+
+// Gap records that the tx signed with sequence was accepted by CheckTx but never committed,
+// e.g. because it was evicted from the mempool. The node no longer expects the sequences
+// that were accepted before, so the account sequence is refetched from the chain.
+func (m *SequenceManager) Gap(sequence uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextKnown = false
+	m.outOfSync = true
+	m.fetch = true
+}
+
+// OutOfSync returns true if the account sequence must be resynced before signing the next tx
+func (m *SequenceManager) OutOfSync() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.outOfSync
+}
+
+// resync returns the sequence that the node expects next, fetching the account sequence
+// from the chain if it is unknown
+func (m *SequenceManager) resync(fetchSequence func() (uint64, error)) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.fetch {
+		sequence, err := fetchSequence()
+		if err != nil {
+			return 0, err
+		}
+		m.raiseNext(sequence)
+	}
+
+	// the rejected txs did not use their sequences, so without knowing what
+	// the node expects, continue from the lowest sequence that was not used
+	next := m.lowestRejected
+	if m.nextKnown {
+		next = m.next
+	}
+	m.outOfSync, m.fetch, m.rejected = false, false, false
+	return next, nil
+}
+
+func (m *SequenceManager) raiseNext(sequence uint64) {
+	if !m.nextKnown || sequence > m.next {
+		m.next = sequence
+		m.nextKnown = true
+	}
+}
+
+// syncSequence resyncs AccountSequence if the sequence manager is out of sync, after waiting for
+// the in-flight txs to be done. Must only be called from the goroutine processing the msg queue.
+func (w *Wallet) syncSequence() {
+	if w.Sequences == nil || !w.Sequences.OutOfSync() {
+		return
+	}
+	if w.Pipeline != nil {
+		w.Pipeline.wait()
+	}
+
+	next, err := w.Sequences.resync(func() (uint64, error) {
+		acc, err := api.GetAccount(w.GRPCURL, w.Bech32Addr, w.ClientCtx)
+		if err != nil {
+			return 0, err
+		}
+		return acc.Sequence, nil
+	})
+	if err != nil {
+		// retried before the next tx is signed
+		w.logger().Error("unable to refetch account sequence", F(FieldError, err))
+		return
+	}
+	if next != w.AccountSequence {
+		w.logger().Warn("resyncing account sequence", F("from", w.AccountSequence), F(FieldSequence, next))
+	}
+	w.AccountSequence = next
+}
+
+// sequenceGap records a gap at the sequence of txItems, which was accepted by CheckTx but never committed
+func (w *Wallet) sequenceGap(txItems TxItems) {
+	if w.Sequences == nil {
+		return
+	}
+	w.logger().Warn("sequence gap detected", F(FieldSequence, txItems.Sequence), F(FieldTxHash, txItems.Hash))
+	w.Sequences.Gap(txItems.Sequence)
+}
+
+// acceptTx records that tx was accepted by CheckTx
+func (w *Wallet) acceptTx(tx authsigning.Tx) {
+	sequence, err := txSequence(tx)
+	if err != nil {
+		w.logger().Error("unable to get accepted tx sequence", F(FieldError, err))
+		return
+	}
+	w.Sequences.Accepted(sequence)
+}
+
+// rejectTx records that tx was rejected by CheckTx with response
+func (w *Wallet) rejectTx(tx authsigning.Tx, response *sdktypes.TxResponse) {
+	sequence, err := txSequence(tx)
+	if err != nil {
+		w.logger().Error("unable to get rejected tx sequence", F(FieldError, err))
+		return
+	}
+	w.Sequences.Rejected(sequence, response)
+}
+
+// retrySequenceMismatch resubmits items of a tx that was rejected because of a sequence mismatch,
+// so that they are signed again with the resynced sequence. Items that have been retried
+// MaxSequenceRetries times are responded to with err instead.
+func (w *Wallet) retrySequenceMismatch(items []MsgQueueItem, response *sdktypes.TxResponse, err error) {
+	retry := []MsgQueueItem{}
+	for _, item := range items {
+		if item.Attempts > w.MaxSequenceRetries {
+			w.EnqueueMsgResponse(item, response, err)
+			continue
+		}
+		retry = append(retry, item)
+	}
+	if len(retry) > 0 {
+		w.logger().Warn("retrying msgs rejected with sequence mismatch", F("msg_count", len(retry)))
+		w.resubmit(retry)
+	}
+}
+
+// parseExpectedSequence parses the sequence expected by the node from the raw_log of a sequence mismatch error
+func parseExpectedSequence(rawLog string) (uint64, bool) {
+	matches := expectedSequenceRegex.FindStringSubmatch(rawLog)
+	if matches == nil {
+		return 0, false
+	}
+	expected, err := strconv.ParseUint(matches[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return expected, true
+}
+
+// txSequence returns the sequence tx was signed with
+func txSequence(tx authsigning.Tx) (uint64, error) {
+	sigs, err := tx.GetSignaturesV2()
+	if err != nil {
+		return 0, err
+	}
+	if len(sigs) == 0 {
+		return 0, fmt.Errorf("tx is not signed")
+	}
+	return sigs[0].Sequence, nil
+}
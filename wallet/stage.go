@@ -0,0 +1,180 @@
+package wallet
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Switcheo/carbon-wallet-go/api"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+// TxStage is a stage that a broadcasted tx reaches on its way to being finalized
+type TxStage int
+
+const (
+	// StageAccepted is reached when the tx is accepted into the mempool by CheckTx.
+	// The tx may still fail, or never be committed.
+	StageAccepted TxStage = iota
+	// StageIncluded is reached when the tx is committed in a block, whether it succeeded or failed
+	StageIncluded
+	// StageFinalized is reached when ConfirmationDepth blocks have been committed after the
+	// successful tx was included. Success callbacks are only run once this stage is reached.
+	StageFinalized
+)
+
+func (s TxStage) String() string {
+	switch s {
+	case StageAccepted:
+		return "accepted"
+	case StageIncluded:
+		return "included"
+	case StageFinalized:
+		return "finalized"
+	default:
+		return fmt.Sprintf("stage(%d)", int(s))
+	}
+}
+
+// StageCallback is called for each msg of a tx when the tx reaches a stage
+type StageCallback func(stage TxStage, response *sdktypes.TxResponse, msg sdktypes.Msg)
+
+// WithStageCallback submits msgs with a callback that is called as their tx reaches each stage.
+// The stages of a tx that is rebroadcasted or resent in halves are reported again for the new tx.
+func WithStageCallback(callback StageCallback) SubmitOption {
+	return func(item *MsgQueueItem) {
+		item.StageCallback = callback
+	}
+}
+
+// runStageCallback runs the stage callback of the item for each of its msgs
+func (item MsgQueueItem) runStageCallback(stage TxStage, response *sdktypes.TxResponse) {
+	if item.StageCallback == nil {
+		return
+	}
+	for _, msg := range item.GetMsgs() {
+		item.StageCallback(stage, response, msg)
+	}
+}
+
+func (w *Wallet) runStageCallback(stage TxStage, response *sdktypes.TxResponse, items []MsgQueueItem) {
+	for _, item := range items {
+		item.runStageCallback(stage, response)
+	}
+}
+
+// finalityPollInterval is the interval between polls for the latest block height while txs await finality
+const finalityPollInterval = time.Second
+
+type awaitingTx struct {
+	txItems  TxItems
+	response *sdktypes.TxResponse
+}
+
+// FinalityTracker holds successful txs until ConfirmationDepth blocks have been committed after
+// they were included. A single goroutine polls for the latest block height while any txs are held.
+type FinalityTracker struct {
+	mu       sync.Mutex
+	awaiting []awaitingTx
+	running  bool
+}
+
+// NewFinalityTracker returns a finality tracker
+func NewFinalityTracker() *FinalityTracker {
+	return &FinalityTracker{}
+}
+
+// Len returns the number of txs awaiting finality
+func (t *FinalityTracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.awaiting)
+}
+
+// await holds the successful tx committed with response until it is finalized
+func (t *FinalityTracker) await(w *Wallet, txItems TxItems, response *sdktypes.TxResponse) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.awaiting = append(t.awaiting, awaitingTx{txItems: txItems, response: response})
+	if !t.running {
+		t.running = true
+		go t.run(w)
+	}
+}
+
+// run finalizes the awaiting txs as blocks are committed, until there are none left
+func (t *FinalityTracker) run(w *Wallet) {
+	ticker := time.NewTicker(finalityPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.StopChannel:
+			t.stopped()
+			return
+		case <-ticker.C:
+		}
+
+		height, err := api.GetLatestBlockHeight(w.GRPCURL, w.ClientCtx)
+		if err != nil {
+			w.logger().Warn("unable to get latest block height for finality", F(FieldError, err))
+			continue
+		}
+
+		finalized, done := t.popFinalized(height, w.ConfirmationDepth)
+		for _, tx := range finalized {
+			w.finalizeTx(tx.txItems, tx.response)
+		}
+		if done {
+			return
+		}
+	}
+}
+
+// popFinalized removes and returns the awaiting txs that are finalized at height.
+// Returns done if no txs are left, in which case the tracker stops running.
+func (t *FinalityTracker) popFinalized(height int64, depth int64) (finalized []awaitingTx, done bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	awaiting := t.awaiting[:0]
+	for _, tx := range t.awaiting {
+		if height >= tx.response.Height+depth {
+			finalized = append(finalized, tx)
+			continue
+		}
+		awaiting = append(awaiting, tx)
+	}
+	for i := len(awaiting); i < len(t.awaiting); i++ {
+		t.awaiting[i] = awaitingTx{}
+	}
+	t.awaiting = awaiting
+
+	if len(t.awaiting) == 0 {
+		t.running = false
+		return finalized, true
+	}
+	return finalized, false
+}
+
+// stopped records that the tracker stopped running with txs left, which must be drained
+func (t *FinalityTracker) stopped() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running = false
+}
+
+// drain removes and returns the txs awaiting finality
+func (t *FinalityTracker) drain() []awaitingTx {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	awaiting := t.awaiting
+	t.awaiting = nil
+	return awaiting
+}
+
+// finalizeTx runs the success callbacks of a successful tx once it is finalized
+func (w *Wallet) finalizeTx(txItems TxItems, response *sdktypes.TxResponse) {
+	w.runStageCallback(StageFinalized, response, txItems.Items)
+	w.runCallback(response, txItems.Items, nil)
+}
@@ -0,0 +1,73 @@
+package wallet
+
+import (
+	"testing"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// awaitingAt returns a tx awaiting finality that was included at height
+func awaitingAt(hash string, height int64) awaitingTx {
+	return awaitingTx{txItems: TxItems{Hash: hash}, response: &sdktypes.TxResponse{TxHash: hash, Height: height}}
+}
+
+func TestFinalityTrackerPopFinalized(t *testing.T) {
+	tracker := NewFinalityTracker()
+	tracker.awaiting = []awaitingTx{awaitingAt("TX1", 10), awaitingAt("TX2", 12)}
+	tracker.running = true
+
+	if finalized, done := tracker.popFinalized(12, 3); len(finalized) != 0 || done {
+		t.Fatalf("expected no txs to be finalized at height 12, got %d", len(finalized))
+	}
+	finalized, done := tracker.popFinalized(13, 3)
+	if len(finalized) != 1 || finalized[0].txItems.Hash != "TX1" || done {
+		t.Fatalf("expected TX1 to be finalized at height 13, got %+v", finalized)
+	}
+	if tracker.Len() != 1 {
+		t.Fatalf("expected TX2 to still be awaiting, got %d txs", tracker.Len())
+	}
+	finalized, done = tracker.popFinalized(15, 3)
+	if len(finalized) != 1 || finalized[0].txItems.Hash != "TX2" || !done {
+		t.Fatalf("expected TX2 to be finalized at height 15, got %+v", finalized)
+	}
+	if tracker.running {
+		t.Fatal("expected tracker to stop running once no txs are left")
+	}
+}
+
+func TestFinalityTrackerDrain(t *testing.T) {
+	tracker := NewFinalityTracker()
+	tracker.awaiting = []awaitingTx{awaitingAt("TX1", 10)}
+	if drained := tracker.drain(); len(drained) != 1 || tracker.Len() != 0 {
+		t.Fatalf("expected the awaiting tx to be drained, got %d", len(drained))
+	}
+}
+
+func TestTxCommittedWithoutConfirmationDepth(t *testing.T) {
+	stages := []TxStage{}
+	var callbackErr error
+	called := false
+	item := MsgQueueItem{
+		ID:  "a",
+		Msg: &banktypes.MsgSend{},
+		Callback: func(_ *sdktypes.TxResponse, _ sdktypes.Msg, err error) {
+			called, callbackErr = true, err
+		},
+		StageCallback: func(stage TxStage, _ *sdktypes.TxResponse, _ sdktypes.Msg) {
+			stages = append(stages, stage)
+		},
+	}
+	w := &Wallet{Finality: NewFinalityTracker()}
+	w.onTxCommitted(TxItems{Hash: "TX1", Items: []MsgQueueItem{item}}, &sdktypes.TxResponse{TxHash: "TX1", Height: 10})
+
+	if !called || callbackErr != nil {
+		t.Fatalf("expected success callback to be run on inclusion, got %v, %v", called, callbackErr)
+	}
+	if len(stages) != 2 || stages[0] != StageIncluded || stages[1] != StageFinalized {
+		t.Fatalf("expected included and finalized stages, got %v", stages)
+	}
+	if w.Finality.Len() != 0 {
+		t.Fatal("expected tx not to await finality")
+	}
+}
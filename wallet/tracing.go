@@ -0,0 +1,100 @@
+package wallet
+
+import (
+	"context"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+const tracerName = "github.com/Switcheo/carbon-wallet-go/wallet"
+
+// WithContext submits msgs with ctx as the parent of their submit span
+func WithContext(ctx context.Context) SubmitOption {
+	return func(item *MsgQueueItem) {
+		item.ctx = ctx
+	}
+}
+
+// tracer returns the tracer of TracerProvider, or a no-op tracer if it is unset
+func (w *Wallet) tracer() trace.Tracer {
+	if w.TracerProvider == nil {
+		return trace.NewNoopTracerProvider().Tracer(tracerName)
+	}
+	return w.TracerProvider.Tracer(tracerName)
+}
+
+// GRPCDialOptions returns the options of the connections used for broadcasting,
+// which propagate the broadcast span to the node if TracerProvider is set
+func (w *Wallet) GRPCDialOptions() []grpc.DialOption {
+	if w.TracerProvider == nil {
+		return nil
+	}
+	return []grpc.DialOption{
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor(otelgrpc.WithTracerProvider(w.TracerProvider))),
+		grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor(otelgrpc.WithTracerProvider(w.TracerProvider))),
+	}
+}
+
+// startSubmitSpan starts the span of submitting item, as a child of the context set by WithContext.
+// The batch spans of the txs that item is sent in are linked to it.
+func (w *Wallet) startSubmitSpan(item *MsgQueueItem, name string) trace.Span {
+	ctx := item.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	item.ctx = nil
+	_, span := w.tracer().Start(ctx, name, trace.WithAttributes(
+		attribute.String("wallet.item_id", item.ID),
+		attribute.Int("wallet.msg_count", len(item.GetMsgs())),
+		attribute.Bool("wallet.async", item.Async),
+	))
+	item.spanContext = span.SpanContext()
+	return span
+}
+
+// startBatchSpan starts the span of signing and broadcasting a tx for items, linked to the submit span of each item
+func (w *Wallet) startBatchSpan(items []MsgQueueItem, msgCount int) (context.Context, trace.Span) {
+	links := []trace.Link{}
+	for _, item := range items {
+		if item.spanContext.IsValid() {
+			links = append(links, trace.Link{SpanContext: item.spanContext})
+		}
+	}
+	return w.tracer().Start(context.Background(), "wallet.batch", trace.WithLinks(links...), trace.WithAttributes(
+		attribute.String("wallet.address", w.Bech32Addr),
+		attribute.Int("wallet.item_count", len(items)),
+		attribute.Int("wallet.msg_count", msgCount),
+	))
+}
+
+// endSpan ends span with the attributes of response, and with err as its status if set
+func endSpan(span trace.Span, response *sdktypes.TxResponse, err error) {
+	if response != nil && response.TxHash != "" {
+		span.SetAttributes(
+			attribute.String("tx.hash", response.TxHash),
+			attribute.String("tx.codespace", response.Codespace),
+			attribute.Int64("tx.code", int64(response.Code)),
+		)
+		if response.Height > 0 {
+			span.SetAttributes(attribute.Int64("tx.height", response.Height))
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// endConfirmSpan ends the confirm span of txItems if it has one
+func endConfirmSpan(txItems TxItems, response *sdktypes.TxResponse, err error) {
+	if txItems.confirmSpan == nil {
+		return
+	}
+	endSpan(txItems.confirmSpan, response, err)
+}
@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"bytes"
+	"fmt"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+)
+
+// ErrInvalidMsg is returned when a submitted msg fails pre-flight validation
+var ErrInvalidMsg = fmt.Errorf("invalid msg")
+
+// MsgValidator validates a submitted msg before it is queued, returning an error to reject it
+type MsgValidator func(msg sdktypes.Msg) error
+
+// validateMsgs validates the msgs of item before it is queued. Each msg must pass ValidateBasic
+// where implemented, be signed by the wallet or by Granter, and pass every validator in MsgValidators.
+func (w *Wallet) validateMsgs(item MsgQueueItem) error {
+	signers, err := w.allowedSigners()
+	if err != nil {
+		return err
+	}
+	for _, msg := range item.GetMsgs() {
+		if msg == nil {
+			return fmt.Errorf("%w: msg is nil", ErrInvalidMsg)
+		}
+		if err := w.validateMsg(msg, signers); err != nil {
+			return fmt.Errorf("%w %s: %v", ErrInvalidMsg, sdktypes.MsgTypeURL(msg), err)
+		}
+	}
+	return nil
+}
+
+func (w *Wallet) validateMsg(msg sdktypes.Msg, allowedSigners [][]byte) error {
+	if msg, ok := msg.(sdktypes.HasValidateBasic); ok {
+		if err := msg.ValidateBasic(); err != nil {
+			return err
+		}
+	}
+
+	if signers, ok := w.msgSigners(msg); ok {
+		for _, signer := range signers {
+			if !containsAddress(allowedSigners, signer) {
+				return fmt.Errorf("signer %s is neither the wallet nor its granter", sdktypes.AccAddress(signer))
+			}
+		}
+	}
+
+	for _, validator := range w.MsgValidators {
+		if err := validator(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// msgSigners returns the signers of msg from the codec of ClientCtx, or from GetSigners for legacy msgs
+// if the codec cannot get them, e.g. because it has no address codec. Returns false if the signers
+// cannot be determined either way.
+func (w *Wallet) msgSigners(msg sdktypes.Msg) ([][]byte, bool) {
+	if w.ClientCtx.Codec != nil {
+		signers, _, err := w.ClientCtx.Codec.GetMsgV1Signers(msg)
+		if err == nil {
+			return signers, true
+		}
+		w.logger().Debug("unable to get msg signers from codec", F("msg_type", sdktypes.MsgTypeURL(msg)), F(FieldError, err))
+	}
+	legacyMsg, ok := msg.(sdktypes.LegacyMsg)
+	if !ok {
+		return nil, false
+	}
+	signers := [][]byte{}
+	for _, signer := range legacyMsg.GetSigners() {
+		signers = append(signers, signer)
+	}
+	return signers, true
+}
+
+// allowedSigners returns the addresses that submitted msgs may be signed by,
+// i.e. the wallet's own address, and Granter if the wallet is a grantee
+func (w *Wallet) allowedSigners() ([][]byte, error) {
+	allowed := [][]byte{w.AccAddress()}
+	if w.IsGrantee() {
+		_, granter, err := bech32.DecodeAndConvert(w.Granter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid granter address %s: %w", w.Granter, err)
+		}
+		allowed = append(allowed, granter)
+	}
+	return allowed, nil
+}
+
+func containsAddress(addresses [][]byte, address []byte) bool {
+	for _, a := range addresses {
+		if bytes.Equal(a, address) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,7 +3,10 @@ package wallet
 import (
 	"context"
 	"fmt"
+	"math"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -25,7 +28,9 @@ import (
 	sdktypes "github.com/cosmos/cosmos-sdk/types"
 	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
 	"github.com/google/uuid"
-	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
 )
 
 // Config
@@ -47,27 +52,90 @@ type SubmitMsgResponse struct {
 
 // MsgQueueItem message queue item
 type MsgQueueItem struct {
-	ID       string
-	Msg      sdktypes.Msg
+	ID  string
+	Msg sdktypes.Msg
+	// Msgs is set instead of Msg for msgs that must be sent together in the same tx, in order
+	Msgs     []sdktypes.Msg
 	Async    bool
+	Priority Priority
+	// Attempts is the number of times the item has been signed and broadcasted
+	Attempts int
 	Callback func(*sdktypes.TxResponse, sdktypes.Msg, error)
+	// StageCallback is called as the tx of the item reaches each stage, if set
+	StageCallback StageCallback
+	// IdempotencyKey deduplicates submissions of the item if set
+	IdempotencyKey string
+	// GasMultiplier and FeeMultiplier are applied to the gas limit and gas price of the tx of the item if set
+	GasMultiplier float64
+	FeeMultiplier float64
+	// ctx is the parent of the submit span of the item, which is linked to by spanContext
+	ctx         context.Context
+	spanContext trace.SpanContext
+}
+
+// SubmitOption sets optional fields of a submitted MsgQueueItem
+type SubmitOption func(*MsgQueueItem)
+
+// WithPriority submits msgs with priority instead of PriorityNormal
+func WithPriority(priority Priority) SubmitOption {
+	return func(item *MsgQueueItem) {
+		item.Priority = priority
+	}
+}
+
+func (item *MsgQueueItem) applyOptions(opts []SubmitOption) {
+	for _, opt := range opts {
+		opt(item)
+	}
+}
+
+// GetMsgs returns the msgs of the item
+func (item MsgQueueItem) GetMsgs() []sdktypes.Msg {
+	if len(item.Msgs) > 0 {
+		return item.Msgs
+	}
+	return []sdktypes.Msg{item.Msg}
+}
+
+// RunCallback runs the item callback for each of its msgs
+func (item MsgQueueItem) RunCallback(response *sdktypes.TxResponse, err error) {
+	if item.Callback == nil {
+		return
+	}
+	for _, msg := range item.GetMsgs() {
+		item.Callback(response, msg, err)
+	}
 }
 
 type TxItems struct {
-	Hash       string
-	Items      []MsgQueueItem
-	CreatedAt  time.Time
-	RetryCount uint
+	Hash          string
+	Sequence      uint64
+	TimeoutHeight uint64
+	Items         []MsgQueueItem
+	CreatedAt     time.Time
+	RetryCount    uint
+	Fee           sdktypes.Coins
+	// Unbroadcast is set for a tx restored from the journal that may never have been broadcasted,
+	// whose items are resent as soon as it provably expires
+	Unbroadcast bool
+	// confirmSpan is the span of confirming the tx, if it is traced
+	confirmSpan trace.Span
 }
 
 // Wallet - used to submit tx
 type Wallet struct {
+	// Label identifies the wallet in logs and metrics
+	Label string
+	// Logger is the logger of the wallet, which logs with the wallet label and redacts msg payloads
+	// when created by ConnectWallet. The default logger is used if unset.
+	Logger                        Logger
 	AccountNumber                 uint64
 	AccountSequence               uint64
 	ChainID                       string
 	PrivKey                       cmcryptotypes.PrivKey
 	PubKey                        cmcryptotypes.PubKey
 	Bech32Addr                    string
+	Granter                       string
 	MainPrefix                    string
 	DefaultGas                    uint64
 	TxTimeoutHeight               int64
@@ -75,13 +143,71 @@ type Wallet struct {
 	UpdateBlockHeightLimiter      *rate.Limiter
 	GRPCURL                       string
 	MsgFlushInterval              time.Duration
-	MsgQueue                      chan MsgQueueItem
+	MaxMsgsPerTx                  int
+	MaxGasPerTx                   uint64
+	MaxTxBytes                    int
+	BisectFailedBatches           bool
+	MsgQueue                      *MsgQueue
+	RetryBatchQueue               chan []MsgQueueItem
 	ResponseChannel               chan SubmitMsgResponse
 	StopChannel                   chan int
 	ConfirmTransactionChannel     chan TxItems
 	ConfirmTransactionMinInterval time.Duration
 	ConfirmTransactionTimeout     time.Duration
-	ClientCtx                     client.Context
+	// ConfirmTransactionBackoff is the backoff between polls for a pending tx
+	ConfirmTransactionBackoff BackoffPolicy
+	// Scheduler runs the waits between polls for pending txs if set, otherwise each wait takes a goroutine
+	Scheduler *Scheduler
+	// ConfirmTransactionFallbackInterval is the min interval between polls for a pending tx when ConfirmationBackend is set
+	ConfirmTransactionFallbackInterval time.Duration
+	ClientCtx                          client.Context
+	// Pipeline broadcasts txs concurrently if set, otherwise txs are broadcasted one at a time
+	Pipeline *BroadcastPipeline
+	// RetryBacklog holds the retried batches that do not fit in RetryBatchQueue if set,
+	// otherwise requeueing a retry waits for space in RetryBatchQueue
+	RetryBacklog *RetryBacklog
+	// Sequences resyncs the account sequence after sequence mismatches and gaps if set,
+	// otherwise the account sequence is refetched after a sequence mismatch. The msgs of the
+	// txs rejected with a sequence mismatch are signed again with the resynced sequence up to
+	// MaxSequenceRetries times.
+	Sequences          *SequenceManager
+	MaxSequenceRetries int
+	// PendingTxs tracks the txs waiting to be confirmed, so that they can be resolved by ConfirmationBackend
+	PendingTxs          *PendingTxs
+	ConfirmationBackend ConfirmationBackend
+	// RebroadcastTimedOutTxs rebroadcasts the msgs of txs that timed out once they can no longer be committed
+	RebroadcastTimedOutTxs bool
+	MaxRebroadcasts        int
+	// ConfirmationDepth is the number of blocks committed after a successful tx before its success callbacks
+	// are run, and Finality holds the txs until then. Success callbacks are run on inclusion if either is unset.
+	ConfirmationDepth int64
+	Finality          *FinalityTracker
+	// MsgValidators are run on each submitted msg before it is queued, in addition to the built-in validation
+	MsgValidators []MsgValidator
+	// MsgRateLimiter rejects msgs submitted faster than its rate with ErrRateLimited if set,
+	// and TxRateLimiter holds back txs until its rate allows them to be sent if set
+	MsgRateLimiter *rate.Limiter
+	TxRateLimiter  *rate.Limiter
+	// Spend rejects msgs that would send more than its spend limits allow if set
+	Spend *SpendLimiter
+	// RemediationPolicies are how msgs of txs that failed with each error class are retried,
+	// and RemediationHook overrides the remediation decided by the policies if set
+	RemediationPolicies map[ErrorClass]RemediationPolicy
+	RemediationHook     RemediationHook
+	// Idempotency deduplicates submissions with idempotency keys if set
+	Idempotency *IdempotencyKeys
+	// DeadLetters receives the async items that failed permanently if set
+	DeadLetters DeadLetterSink
+	// Journal records the submitted msgs and the txs they were sent in if set, to be replayed after a restart
+	Journal Journal
+	// Lifecycle stops all goroutines of the wallet on Shutdown if set
+	Lifecycle *Lifecycle
+	// Metrics collects the metrics of the wallet if set
+	Metrics *Metrics
+	// TracerProvider traces msgs from their submission to the confirmation of their txs if set
+	TracerProvider trace.TracerProvider
+	// GRPCConn is used for broadcasting if set, otherwise a new connection is opened for each broadcast
+	GRPCConn *grpc.ClientConn
 }
 
 // AccAddress -
@@ -97,6 +223,12 @@ func (w *Wallet) IncrementAccountSequence() {
 // Everytime this is called, the nonce will be incremented.
 // If there is a nonce error after broadcast, it'll refetch the nonce.
 func (w *Wallet) CreateAndSignTx(msgs []sdktypes.Msg) (tx authsigning.Tx, err error) {
+	return w.createAndSignTx(msgs, 1, 1)
+}
+
+// createAndSignTx creates and signs a tx like CreateAndSignTx, with gasMultiplier applied to its
+// gas limit, and feeMultiplier applied to its gas price
+func (w *Wallet) createAndSignTx(msgs []sdktypes.Msg, gasMultiplier float64, feeMultiplier float64) (tx authsigning.Tx, err error) {
 	txConfig := GetTxConfig()
 	txBuilder := txConfig.NewTxBuilder()
 
@@ -106,19 +238,26 @@ func (w *Wallet) CreateAndSignTx(msgs []sdktypes.Msg) (tx authsigning.Tx, err er
 	// Set messages
 	err = txBuilder.SetMsgs(msgs...)
 	if err != nil {
-		log.Error("setmsg err: ", err)
+		w.logger().Error("unable to set tx msgs", F(FieldSequence, accountSequence), F(FieldError, err))
 		return nil, err
 	}
 
 	// Set other tx details
 	var feeCoins types.Coins = make([]types.Coin, 1)
-	feeAmount := utils.MustDecShiftInt(sdkmath.LegacyNewDec(int64(len(txBuilder.GetTx().GetMsgs()))), 8)
+	gasLimit := txFeeAmount(msgs)
+	if gasMultiplier > 1 {
+		gasLimit = sdkmath.LegacyNewDecFromInt(gasLimit).Mul(multiplierDec(gasMultiplier)).Ceil().TruncateInt()
+	}
+	feeAmount := gasLimit
+	if feeMultiplier > 1 {
+		feeAmount = sdkmath.LegacyNewDecFromInt(gasLimit).Mul(multiplierDec(feeMultiplier)).Ceil().TruncateInt()
+	}
 	feeCoins[0] = types.Coin{
 		Denom:  constants.MainDenom,
 		Amount: feeAmount,
 	}
 	txBuilder.SetFeeAmount(feeCoins)
-	txBuilder.SetGasLimit(feeAmount.Uint64())
+	txBuilder.SetGasLimit(gasLimit.Uint64())
 
 	if w.TxTimeoutHeight != 0 {
 		timeoutHeight := w.GetCurrentBlockHeight() + w.TxTimeoutHeight
@@ -140,7 +279,7 @@ func (w *Wallet) CreateAndSignTx(msgs []sdktypes.Msg) (tx authsigning.Tx, err er
 
 	err = txBuilder.SetSignatures(sigV2)
 	if err != nil {
-		log.Error("setsig err: ", err)
+		w.logger().Error("unable to set tx signer infos", F(FieldSequence, accountSequence), F(FieldError, err))
 		return nil, err
 	}
 
@@ -156,21 +295,31 @@ func (w *Wallet) CreateAndSignTx(msgs []sdktypes.Msg) (tx authsigning.Tx, err er
 		signingtypes.SignMode_SIGN_MODE_DIRECT, signerData,
 		txBuilder, w.PrivKey, txConfig, accountSequence)
 	if err != nil {
-		log.Error("sign err: ", err)
+		w.logger().Error("unable to sign tx", F(FieldSequence, accountSequence), F(FieldError, err))
 		return nil, err
 	}
 
 	err = txBuilder.SetSignatures(sigV2)
 	if err != nil {
-		log.Error("setsig err: ", err)
+		w.logger().Error("unable to set tx signatures", F(FieldSequence, accountSequence), F(FieldError, err))
 		return nil, err
 	}
 
 	return txBuilder.GetTx(), nil
 }
 
+// multiplierDec returns m as a dec with 6 decimal places
+func multiplierDec(m float64) sdkmath.LegacyDec {
+	return sdkmath.LegacyNewDecWithPrec(int64(m*1e6), 6)
+}
+
+// txFeeAmount returns the fee amount for msgs, which is also used as the tx gas limit
+func txFeeAmount(msgs []sdktypes.Msg) sdkmath.Int {
+	return utils.MustDecShiftInt(sdkmath.LegacyNewDec(int64(msgCount(msgs))), 8)
+}
+
 // UpdateBlockHeight updates the block height using rate limiter to update CurrentBlockHeight.
-// CurrentBlockHeight is used to calculate tx.TimeoutHeight
+// CurrentBlockHeight is used to calculate tx.TimeoutHeight, and is accessed atomically as it is read by concurrent broadcasts
 func (w *Wallet) UpdateBlockHeight() {
 	if !w.UpdateBlockHeightLimiter.Allow() {
 		return
@@ -180,17 +329,25 @@ func (w *Wallet) UpdateBlockHeight() {
 	if err != nil {
 		panic("unable to get latest block height of chain")
 	}
-	w.CurrentBlockHeight = blockHeight
+	atomic.StoreInt64(&w.CurrentBlockHeight, blockHeight)
 }
 
 // GetCurrentBlockHeight calls UpdateBlockHeight before returning CurrentBlockHeight
 func (w *Wallet) GetCurrentBlockHeight() int64 {
 	w.UpdateBlockHeight()
-	return w.CurrentBlockHeight
+	return atomic.LoadInt64(&w.CurrentBlockHeight)
 }
 
 // BroadcastTx - broadcasts a tx via grpc
 func (w *Wallet) BroadcastTx(tx authsigning.Tx, mode BroadcastMode, items []MsgQueueItem) (txResp *sdktypes.TxResponse, err error) {
+	return w.broadcastTx(context.Background(), tx, mode, items)
+}
+
+// broadcastTx broadcasts tx like BroadcastTx, in a broadcast span that is a child of ctx
+func (w *Wallet) broadcastTx(ctx context.Context, tx authsigning.Tx, mode BroadcastMode, items []MsgQueueItem) (txResp *sdktypes.TxResponse, err error) {
+	ctx, span := w.tracer().Start(ctx, "wallet.broadcast", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { endSpan(span, txResp, err) }()
+
 	switch mode {
 	case BroadcastModeAsync:
 	case BroadcastModeSync:
@@ -199,82 +356,173 @@ func (w *Wallet) BroadcastTx(tx authsigning.Tx, mode BroadcastMode, items []MsgQ
 		return
 	}
 
+	sequence, _ := txSequence(tx)
+	logger := w.loggerFor(ctx).With(F(FieldSequence, sequence))
+
 	txConfig := GetTxConfig()
 	txBytes, err := txConfig.TxEncoder()(tx)
 	if err != nil {
-		log.Error("encoding err: ", err)
+		logger.Error("unable to encode tx", F(FieldError, err))
 		return nil, err
 	}
 
 	// Broadcast the tx via gRPC. We create a new client for the Protobuf Tx
 	// service.
-	grpcConn, err := api.GetGRPCConnection(w.GRPCURL, w.ClientCtx)
+	grpcConn, closeConn, err := w.getGRPCConnection()
 	if err != nil {
-		log.Error("grpc error: ", err)
+		logger.Error("unable to open grpc connection", F(FieldError, err))
 		return nil, err
 	}
-	defer grpcConn.Close()
+	defer closeConn()
 
 	txClient := txtypes.NewServiceClient(grpcConn)
 
-	log.Info("Broadcasting Txn with messages: ", tx.GetMsgs())
+	logger.Debug("broadcasting tx", F(FieldMsgs, tx.GetMsgs()))
 
 	// We then call the BroadcastTx method on this client.
 	grpcRes, err := txClient.BroadcastTx(
-		context.Background(),
+		ctx,
 		&txtypes.BroadcastTxRequest{
 			Mode:    txtypes.BroadcastMode_BROADCAST_MODE_SYNC,
 			TxBytes: txBytes, // Proto-binary of the signed transaction, see previous step.
 		},
 	)
 	if err != nil {
-		log.Error(err)
+		logger.Error("unable to broadcast tx", F(FieldError, err))
 		return nil, err
 	}
 
 	if grpcRes.TxResponse.Code != 0 {
-		err = fmt.Errorf("Broadcast failed with code: %+v, raw_log: %+v\n", grpcRes.TxResponse.Code, grpcRes.TxResponse.RawLog)
-		log.Error(err)
+		err = NewTxError(grpcRes.TxResponse, false)
+		logger.Error("tx rejected by CheckTx", F(FieldTxHash, grpcRes.TxResponse.TxHash), F(FieldError, err))
+
+		if w.Sequences != nil {
+			// the sequence is resynced before the next tx is signed
+			w.rejectTx(tx, grpcRes.TxResponse)
+			return grpcRes.TxResponse, err
+		}
 
 		// handle account nonce mismatch error
-		if grpcRes.TxResponse.Code == 32 { // 32 is nonce error
-			acc, err := api.GetAccount(w.GRPCURL, w.Bech32Addr, w.ClientCtx)
-			if err != nil {
-				err = fmt.Errorf("Unable to refetch account sequence: %+v\n", err)
-				log.Error(err)
+		if responseIs(grpcRes.TxResponse, ErrSequenceMismatch) {
+			acc, fetchErr := api.GetAccount(w.GRPCURL, w.Bech32Addr, w.ClientCtx)
+			if fetchErr != nil {
+				err = fmt.Errorf("%w, unable to refetch account sequence: %v", err, fetchErr)
+				logger.Error("unable to refetch account sequence", F(FieldError, fetchErr))
 				return grpcRes.TxResponse, err
 			}
 			w.AccountSequence = acc.Sequence
+		} else if !responseIs(grpcRes.TxResponse, ErrTxInMempoolCache) {
+			// the tx was rejected by CheckTx so its sequence was not used
+			w.resetAccountSequence(tx)
 		}
 		return grpcRes.TxResponse, err
 	}
 
-	txHash := grpcRes.TxResponse.TxHash
-	log.Info("Broadcasted tx hash: ", txHash)
-	w.ConfirmTransactionChannel <- TxItems{Hash: grpcRes.TxResponse.TxHash, CreatedAt: time.Now(), RetryCount: 0, Items: items}
+	if w.Sequences != nil {
+		w.acceptTx(tx)
+	}
+
+	logger.Info("broadcasted tx", F(FieldTxHash, grpcRes.TxResponse.TxHash))
+	w.trackTx(ctx, tx, grpcRes.TxResponse, items)
 
 	return grpcRes.TxResponse, nil
 }
 
+// trackTx tracks tx accepted by CheckTx with response as pending, until it is confirmed.
+// The tx is confirmed in a confirm span that is a child of ctx.
+func (w *Wallet) trackTx(ctx context.Context, tx authsigning.Tx, response *sdktypes.TxResponse, items []MsgQueueItem) {
+	w.journalBroadcast(response.TxHash)
+	w.runStageCallback(StageAccepted, response, items)
+	sequence, _ := txSequence(tx)
+	txItems := TxItems{Hash: response.TxHash, Sequence: sequence, TimeoutHeight: tx.GetTimeoutHeight(), CreatedAt: time.Now(), RetryCount: 0, Items: items, Fee: tx.GetFee()}
+	if trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		_, txItems.confirmSpan = w.tracer().Start(ctx, "wallet.confirm", trace.WithAttributes(attribute.String("tx.hash", response.TxHash)))
+	}
+	w.PendingTxs.Add(txItems)
+	w.sendConfirmTransaction(txItems)
+}
+
+// resetAccountSequence resets AccountSequence to the sequence tx was signed with
+func (w *Wallet) resetAccountSequence(tx authsigning.Tx) {
+	sequence, err := txSequence(tx)
+	if err != nil {
+		w.logger().Error("unable to get tx sequence to reset account sequence", F(FieldError, err))
+		return
+	}
+	w.AccountSequence = sequence
+}
+
+// getGRPCConnection returns GRPCConn if it is set, otherwise a new connection.
+// The returned func must be called once done with the connection.
+func (w *Wallet) getGRPCConnection() (*grpc.ClientConn, func(), error) {
+	if w.GRPCConn != nil {
+		return w.GRPCConn, func() {}, nil
+	}
+	grpcConn, err := api.GetGRPCConnection(w.GRPCURL, w.ClientCtx, w.GRPCDialOptions()...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return grpcConn, func() { grpcConn.Close() }, nil
+}
+
 // SubmitMsg - submits a sdk.Msg to for broadcasting
-func (w *Wallet) SubmitMsg(msg sdktypes.Msg) (*sdktypes.TxResponse, error) {
-	id := uuid.New().String()
+func (w *Wallet) SubmitMsg(msg sdktypes.Msg, opts ...SubmitOption) (*sdktypes.TxResponse, error) {
 	item := MsgQueueItem{
-		ID:    id,
+		ID:    uuid.New().String(),
 		Msg:   msg,
 		Async: false,
 	}
-	w.MsgQueue <- item
+	item.applyOptions(opts)
+	span := w.startSubmitSpan(&item, "wallet.SubmitMsg")
+	response, err := w.submitAndWait(item)
+	endSpan(span, response, err)
+	return response, err
+}
+
+// SubmitMsgs submits msgs to be broadcasted together in the same tx, in order
+func (w *Wallet) SubmitMsgs(msgs []sdktypes.Msg, opts ...SubmitOption) (*sdktypes.TxResponse, error) {
+	if len(msgs) == 0 {
+		return nil, fmt.Errorf("no msgs to submit")
+	}
+	item := MsgQueueItem{
+		ID:    uuid.New().String(),
+		Msgs:  msgs,
+		Async: false,
+	}
+	item.applyOptions(opts)
+	span := w.startSubmitSpan(&item, "wallet.SubmitMsgs")
+	response, err := w.submitAndWait(item)
+	endSpan(span, response, err)
+	return response, err
+}
+
+// submitAndWait enqueues item and waits for its response
+func (w *Wallet) submitAndWait(item MsgQueueItem) (*sdktypes.TxResponse, error) {
+	// invalid msgs are rejected before their idempotency key is taken
+	if err := w.validateMsgs(item); err != nil {
+		return nil, err
+	}
+	if existing, ok := w.deduplicate(item); ok {
+		w.logger().Info("msgs with idempotency key were already submitted", F(FieldItemID, item.ID), F("idempotency_key", item.IdempotencyKey))
+		return w.waitIdempotent(existing)
+	}
+	if err := w.queueItem(item, false); err != nil {
+		return nil, err
+	}
 	for {
-		msgResponse := <-w.ResponseChannel
-		if strings.EqualFold(msgResponse.ID, id) {
-			return msgResponse.Response, msgResponse.Error
+		select {
+		case msgResponse := <-w.ResponseChannel:
+			if strings.EqualFold(msgResponse.ID, item.ID) {
+				return msgResponse.Response, msgResponse.Error
+			}
+		case <-w.StopChannel:
+			return nil, ErrWalletClosed
 		}
 	}
 }
 
 // SubmitMsgAsync non-blocking submit
-func (w *Wallet) SubmitMsgAsync(msg sdktypes.Msg, callback func(*sdktypes.TxResponse, sdktypes.Msg, error)) {
+func (w *Wallet) SubmitMsgAsync(msg sdktypes.Msg, callback func(*sdktypes.TxResponse, sdktypes.Msg, error), opts ...SubmitOption) {
 	id := uuid.New().String()
 	item := MsgQueueItem{
 		ID:       id,
@@ -282,45 +530,231 @@ func (w *Wallet) SubmitMsgAsync(msg sdktypes.Msg, callback func(*sdktypes.TxResp
 		Async:    true,
 		Callback: callback,
 	}
-	w.MsgQueue <- item
+	item.applyOptions(opts)
+	w.submitAsync(item, false, "wallet.SubmitMsgAsync")
+}
+
+// TrySubmitMsgAsync non-blocking submit that returns ErrQueueFull immediately
+// instead of waiting for space in the msg queue. callback is also called with any error returned.
+func (w *Wallet) TrySubmitMsgAsync(msg sdktypes.Msg, callback func(*sdktypes.TxResponse, sdktypes.Msg, error), opts ...SubmitOption) error {
+	item := MsgQueueItem{
+		ID:       uuid.New().String(),
+		Msg:      msg,
+		Async:    true,
+		Callback: callback,
+	}
+	item.applyOptions(opts)
+	return w.submitAsync(item, true, "wallet.TrySubmitMsgAsync")
+}
+
+// SubmitMsgsAsync non-blocking submit of msgs to be broadcasted together in the same tx, in order.
+// callback is called once for each of msgs, including with any error returned.
+func (w *Wallet) SubmitMsgsAsync(msgs []sdktypes.Msg, callback func(*sdktypes.TxResponse, sdktypes.Msg, error), opts ...SubmitOption) error {
+	if len(msgs) == 0 {
+		return fmt.Errorf("no msgs to submit")
+	}
+	item := MsgQueueItem{
+		ID:       uuid.New().String(),
+		Msgs:     msgs,
+		Async:    true,
+		Callback: callback,
+	}
+	item.applyOptions(opts)
+	return w.submitAsync(item, false, "wallet.SubmitMsgsAsync")
+}
+
+// TrySubmitMsgsAsync is SubmitMsgsAsync that returns ErrQueueFull immediately
+// instead of waiting for space in the msg queue
+func (w *Wallet) TrySubmitMsgsAsync(msgs []sdktypes.Msg, callback func(*sdktypes.TxResponse, sdktypes.Msg, error), opts ...SubmitOption) error {
+	if len(msgs) == 0 {
+		return fmt.Errorf("no msgs to submit")
+	}
+	item := MsgQueueItem{
+		ID:       uuid.New().String(),
+		Msgs:     msgs,
+		Async:    true,
+		Callback: callback,
+	}
+	item.applyOptions(opts)
+	return w.submitAsync(item, true, "wallet.TrySubmitMsgsAsync")
 }
 
-// ProcessMsgQueue process the msg queue
+// submitAsync enqueues the async item, without blocking if try is true. If it is not queued,
+// it is dead-lettered and its callback is called with the error, which is also returned.
+func (w *Wallet) submitAsync(item MsgQueueItem, try bool, spanName string) error {
+	span := w.startSubmitSpan(&item, spanName)
+	err := w.enqueue(item, try)
+	endSpan(span, nil, err)
+	if err != nil {
+		w.deadLetter(item, nil, err)
+		item.RunCallback(nil, err)
+	}
+	return err
+}
+
+// enqueue pushes item to the msg queue, without blocking if try is true.
+// A bulk item that was dropped to make space is failed with ErrQueueFull.
+// An async item with an idempotency key that was already submitted gets the result of that submission instead.
+// Items with msgs that fail pre-flight validation are rejected with ErrInvalidMsg.
+func (w *Wallet) enqueue(item MsgQueueItem, try bool) error {
+	if err := w.validateMsgs(item); err != nil {
+		return err
+	}
+	if item.Async {
+		if existing, ok := w.deduplicate(item); ok {
+			w.logger().Info("msgs with idempotency key were already submitted", F(FieldItemID, item.ID), F("idempotency_key", item.IdempotencyKey))
+			go func() {
+				response, err := w.waitIdempotent(existing)
+				item.RunCallback(response, err)
+			}()
+			return nil
+		}
+	}
+	return w.queueItem(item, try)
+}
+
+// queueItem pushes item to the msg queue once it is validated and deduplicated, counting it towards
+// the wallet's limits and recording it in the journal. The idempotency key of item is forgotten if it is not queued.
+func (w *Wallet) queueItem(item MsgQueueItem, try bool) error {
+	unlimit, err := w.checkLimits(item)
+	if err != nil {
+		w.forgetIdempotent(item)
+		return err
+	}
+	if err := w.journalEnqueued(item); err != nil {
+		unlimit()
+		w.forgetIdempotent(item)
+		return err
+	}
+	push := w.MsgQueue.Push
+	if try {
+		push = w.MsgQueue.TryPush
+	}
+	dropped, err := push(item)
+	if dropped != nil {
+		w.logger().Warn("msg queue is full, dropped bulk msg", F(FieldItemID, dropped.ID))
+		w.EnqueueMsgResponse(*dropped, nil, ErrQueueFull)
+	}
+	if err != nil {
+		unlimit()
+		w.journalDone([]MsgQueueItem{item})
+		w.forgetIdempotent(item)
+	}
+	return err
+}
+
+// QueueDepth returns the number of items in the msg queue
+func (w *Wallet) QueueDepth() int {
+	return w.MsgQueue.Len()
+}
+
+// QueueFillRatio returns the number of items in the msg queue as a ratio of its capacity
+func (w *Wallet) QueueFillRatio() float64 {
+	return w.MsgQueue.FillRatio()
+}
+
+// ProcessMsgQueue process the msg queue. Queued msgs are sent in as many sequential txs
+// as needed to keep each tx within the wallet's batch limits.
 func (w *Wallet) ProcessMsgQueue() {
-	items := []MsgQueueItem{}
-	msgs := []sdktypes.Msg{}
+	// retried batches are sent as is, without merging them with other msgs
+	for {
+		items, ok := w.nextRetryBatch()
+		if !ok {
+			break
+		}
+		w.processBatch(items)
+	}
 
 	for {
 		select {
-		case item := <-w.MsgQueue:
-			items = append(items, item)
-			msgs = append(msgs, item.Msg)
-			continue
+		case <-w.StopChannel:
+			// the msgs left are failed on shutdown
+			return
 		default:
 		}
-		break
+		items := w.nextBatch()
+		if len(items) == 0 {
+			return
+		}
+		w.processBatch(items)
 	}
+}
 
-	if len(items) == 0 {
-		return
+// processBatch signs items as a single tx and broadcasts it through the pipeline if there is one,
+// otherwise broadcasts it before returning
+func (w *Wallet) processBatch(items []MsgQueueItem) {
+	w.waitTxRateLimit()
+	if w.Pipeline != nil {
+		w.Pipeline.acquire()
+	}
+	w.syncSequence()
+
+	msgs := []sdktypes.Msg{}
+	gasMultiplier, feeMultiplier := 1.0, 1.0
+	for i, item := range items {
+		msgs = append(msgs, item.GetMsgs()...)
+		items[i].Attempts++
+		gasMultiplier = math.Max(gasMultiplier, item.GasMultiplier)
+		feeMultiplier = math.Max(feeMultiplier, item.FeeMultiplier)
 	}
 
-	tx, err := w.CreateAndSignTx(msgs)
+	batchID := uuid.New().String()
+	ctx, batchSpan := w.startBatchSpan(items, len(msgs))
+	ctx = withBatchID(ctx, batchID)
+	batchSpan.SetAttributes(attribute.String("wallet.batch_id", batchID))
+	_, signSpan := w.tracer().Start(ctx, "wallet.sign")
+	signStart := time.Now()
+	tx, err := w.createAndSignTx(w.WrapMsgs(msgs), gasMultiplier, feeMultiplier)
+	endSpan(signSpan, nil, err)
 	if err != nil {
-		log.Error("create ang sign tx err: ", err)
+		w.loggerFor(ctx).Error("unable to create and sign tx", F(FieldError, err))
+		endSpan(batchSpan, nil, err)
+		if w.Pipeline != nil {
+			w.Pipeline.release()
+		}
 		for _, item := range items {
 			w.EnqueueMsgResponse(item, &sdktypes.TxResponse{}, err)
 		}
 		return
 	}
 
-	var responseErr error
-	response, err := w.BroadcastTx(tx, BroadcastModeSync, items)
-	if err != nil {
-		responseErr = err
+	w.observeSigned(len(msgs), signStart)
+	if sequence, err := txSequence(tx); err == nil {
+		batchSpan.SetAttributes(attribute.Int64("tx.sequence", int64(sequence)))
+	}
+	w.journalSigned(tx, items)
+
+	if w.Pipeline == nil {
+		w.broadcastBatch(ctx, tx, items)
+		return
 	}
+	w.Pipeline.dispatch(func() {
+		w.broadcastBatch(ctx, tx, items)
+	})
+}
+
+// broadcastBatch broadcasts tx signed for items and responds to items. The batch span of ctx
+// is ended once the tx is broadcasted, and the tx is confirmed in a child span of it.
+func (w *Wallet) broadcastBatch(ctx context.Context, tx authsigning.Tx, items []MsgQueueItem) {
+	broadcastStart := time.Now()
+	response, err := w.broadcastTx(ctx, tx, BroadcastModeSync, items)
+	endSpan(trace.SpanFromContext(ctx), response, err)
+	if err != nil && ClassifyError(response) != ErrorClassTxInMempool {
+		// a tx already in the mempool is confirmed like any other broadcasted tx
+		w.journalRejected(tx)
+	}
+	w.observeBroadcast(response, broadcastStart)
+	if w.shouldBisect(items, response) {
+		w.loggerFor(ctx).Warn("tx failed, retrying in halves", F(FieldTxHash, response.TxHash), F("msg_count", len(items)), F("code", response.Code))
+		w.resubmit(bisect(items))
+		return
+	}
+	responseErr := err
 	if response != nil && response.Code != 0 {
-		responseErr = fmt.Errorf("submit msg failed: err: %v, code: %d, raw_log: %v\n", err, response.Code, response.RawLog)
+		if w.remediateRejected(ctx, tx, items, response, err) {
+			return
+		}
+		responseErr = fmt.Errorf("submit msg failed: %w", err)
 	}
 
 	for _, item := range items {
@@ -330,7 +764,19 @@ func (w *Wallet) ProcessMsgQueue() {
 
 // EnqueueMsgResponse enqueue msg response
 func (w *Wallet) EnqueueMsgResponse(item MsgQueueItem, response *sdktypes.TxResponse, err error) {
+	if !item.Async || err != nil {
+		w.completeIdempotent(item, response, err)
+	}
+	if err != nil {
+		w.journalDone([]MsgQueueItem{item})
+	}
 	if item.Async {
+		// async msgs are otherwise responded to once their tx is confirmed, which
+		// will not happen if the tx could not be broadcasted
+		if err != nil {
+			w.deadLetter(item, response, err)
+			item.RunCallback(response, err)
+		}
 		return
 	}
 
@@ -339,29 +785,105 @@ func (w *Wallet) EnqueueMsgResponse(item MsgQueueItem, response *sdktypes.TxResp
 		Response: response,
 		Error:    err,
 	}
-	w.ResponseChannel <- msgResponse
+	select {
+	case w.ResponseChannel <- msgResponse:
+	case <-w.StopChannel:
+		// the submitter stopped waiting with ErrWalletClosed
+	}
 }
 
-// RunProcessMsgQueue process msg queue in intervals
+// RunProcessMsgQueue flushes the msg queue when a full batch of msgs is queued, or when the oldest
+// queued msg has waited for MsgFlushInterval, whichever is earlier. It does not wake up while the queue is empty.
+// Once the wallet is shutting down, queued msgs are flushed without waiting.
 func (w *Wallet) RunProcessMsgQueue() {
+	if w.Pipeline != nil {
+		// the in-flight broadcasts are done before the wallet is stopped
+		defer w.Pipeline.wait()
+	}
 	for {
 		select {
 		case <-w.StopChannel:
 			return
-		default:
-			interval := w.MsgFlushInterval
-			if interval == 0 {
-				interval = 100 * time.Millisecond
-			}
-			time.Sleep(interval)
-			w.ProcessMsgQueue()
+		case items := <-w.RetryBatchQueue:
+			w.refillRetries()
+			w.processBatch(items)
+			continue
+		case <-w.MsgQueue.Ready():
+		}
+
+		oldest, ok := w.MsgQueue.OldestEnqueuedAt()
+		if !ok {
+			// the items that signalled ready were flushed already
+			continue
+		}
+		if !w.waitForBatch(oldest) {
+			return
 		}
+		w.ProcessMsgQueue()
 	}
 }
 
-// Disconnect disconnect wallet
+// waitForBatch waits until a full batch of msgs is queued, or until MsgFlushInterval has passed
+// since oldest. Returns false if the wallet was stopped while waiting.
+func (w *Wallet) waitForBatch(oldest time.Time) bool {
+	linger := time.NewTimer(time.Until(oldest.Add(w.MsgFlushInterval)))
+	defer linger.Stop()
+
+	for !w.batchReady() {
+		select {
+		case <-w.StopChannel:
+			return false
+		case <-w.closingChannel():
+			return true
+		case <-linger.C:
+			return true
+		case <-w.MsgQueue.Ready():
+		}
+	}
+	return true
+}
+
+// Disconnect disconnects the wallet immediately, failing the queued msgs and the pending txs with
+// ErrWalletClosed. Use Shutdown to send the queued msgs and wait for the pending txs first.
 func (w *Wallet) Disconnect() {
-	w.StopChannel <- 1
+	if w.Lifecycle != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w.Shutdown(ctx)
+		return
+	}
+
+	if !w.closeStopChannel() {
+		return
+	}
+	w.unregisterMetrics()
+	if w.Scheduler != nil {
+		w.Scheduler.Stop()
+	}
+	if w.ConfirmationBackend != nil {
+		w.ConfirmationBackend.Stop()
+	}
+	if w.GRPCConn != nil {
+		w.GRPCConn.Close()
+	}
+}
+
+// stopChannelMu guards closing the StopChannel of a wallet without a Lifecycle
+var stopChannelMu sync.Mutex
+
+// closeStopChannel closes StopChannel so that every goroutine waiting on it returns, rather than sending
+// a value that only one of them receives. Returns false if it was already closed.
+func (w *Wallet) closeStopChannel() bool {
+	stopChannelMu.Lock()
+	defer stopChannelMu.Unlock()
+
+	select {
+	case <-w.StopChannel:
+		return false
+	default:
+		close(w.StopChannel)
+		return true
+	}
 }
 
 func GetTxConfig() client.TxConfig {
@@ -0,0 +1,87 @@
+package wallet
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+func TestDisconnectWithoutLifecycleStopsEveryGoroutine(t *testing.T) {
+	w := &Wallet{StopChannel: make(chan int, 3)}
+
+	var stopped sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		stopped.Add(1)
+		go func() {
+			defer stopped.Done()
+			<-w.StopChannel
+		}()
+	}
+	w.Disconnect()
+	// disconnecting again does nothing
+	w.Disconnect()
+
+	done := make(chan struct{})
+	go func() {
+		stopped.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected every goroutine waiting on StopChannel to return")
+	}
+}
+
+func TestAsyncSubmitFailuresRunCallbacks(t *testing.T) {
+	msgs := []sdktypes.Msg{&banktypes.MsgSend{}, &banktypes.MsgSend{}}
+	submits := map[string]func(w *Wallet, callback func(*sdktypes.TxResponse, sdktypes.Msg, error)) error{
+		"SubmitMsgAsync": func(w *Wallet, callback func(*sdktypes.TxResponse, sdktypes.Msg, error)) error {
+			// SubmitMsgAsync only reports the error to the callback
+			w.SubmitMsgAsync(msgs[0], callback)
+			return nil
+		},
+		"TrySubmitMsgAsync": func(w *Wallet, callback func(*sdktypes.TxResponse, sdktypes.Msg, error)) error {
+			return w.TrySubmitMsgAsync(msgs[0], callback)
+		},
+		"SubmitMsgsAsync": func(w *Wallet, callback func(*sdktypes.TxResponse, sdktypes.Msg, error)) error {
+			return w.SubmitMsgsAsync(msgs, callback)
+		},
+		"TrySubmitMsgsAsync": func(w *Wallet, callback func(*sdktypes.TxResponse, sdktypes.Msg, error)) error {
+			return w.TrySubmitMsgsAsync(msgs, callback)
+		},
+	}
+	for name, submit := range submits {
+		deadLetters := NewMemoryDeadLetterSink()
+		w := &Wallet{
+			PubKey:      secp256k1.GenPrivKey().PubKey(),
+			MsgQueue:    NewMsgQueue(nil, 0, OverflowBlock),
+			DeadLetters: deadLetters,
+		}
+		w.MsgQueue.Close()
+
+		var callbackErrs []error
+		err := submit(w, func(_ *sdktypes.TxResponse, _ sdktypes.Msg, err error) {
+			callbackErrs = append(callbackErrs, err)
+		})
+		if name != "SubmitMsgAsync" && !errors.Is(err, ErrWalletClosed) {
+			t.Fatalf("%s: expected ErrWalletClosed, got %v", name, err)
+		}
+		if len(callbackErrs) == 0 {
+			t.Fatalf("%s: expected callback to be called", name)
+		}
+		for _, err := range callbackErrs {
+			if !errors.Is(err, ErrWalletClosed) {
+				t.Fatalf("%s: expected callback with ErrWalletClosed, got %v", name, err)
+			}
+		}
+		if letters, _ := deadLetters.List(); len(letters) != 1 {
+			t.Fatalf("%s: expected 1 dead letter, got %d", name, len(letters))
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	rpchttp "github.com/cometbft/cometbft/rpc/client/http"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	"github.com/cosmos/cosmos-sdk/types"
+)
+
+const websocketSubscriber = "carbon-wallet-go"
+
+// WebsocketConfirmation is a ConfirmationBackend that subscribes to the Tx events of the wallet's
+// txs through the CometBFT RPC websocket, and resolves pending txs as soon as they are committed.
+// The subscription is restarted whenever it is dropped.
+type WebsocketConfirmation struct {
+	// RPCURL is the CometBFT RPC address, e.g. tcp://localhost:26657
+	RPCURL string
+	// ReconnectInterval is the time to wait before restarting a dropped subscription
+	ReconnectInterval time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewWebsocketConfirmation returns a websocket confirmation backend for the CometBFT RPC at rpcURL
+func NewWebsocketConfirmation(rpcURL string) *WebsocketConfirmation {
+	return &WebsocketConfirmation{
+		RPCURL:            rpcURL,
+		ReconnectInterval: 3 * time.Second,
+		stop:              make(chan struct{}),
+	}
+}
+
+// Start subscribes to the Tx events of w in the background
+func (c *WebsocketConfirmation) Start(w *Wallet) error {
+	go func() {
+		for {
+			err := c.subscribe(w)
+			select {
+			case <-c.stop:
+				return
+			default:
+			}
+			w.logger().Warn("tx events subscription dropped, resubscribing", F("reconnect_interval", c.ReconnectInterval.String()), F(FieldError, err))
+			select {
+			case <-c.stop:
+				return
+			case <-time.After(c.ReconnectInterval):
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the subscription
+func (c *WebsocketConfirmation) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+// subscribe resolves pending txs from the Tx events of w until the subscription is dropped or stopped
+func (c *WebsocketConfirmation) subscribe(w *Wallet) error {
+	client, err := rpchttp.New(c.RPCURL, "/websocket")
+	if err != nil {
+		return err
+	}
+	if err := client.Start(); err != nil {
+		return err
+	}
+	defer client.Stop()
+
+	// every tx signed by the wallet emits its signer's account sequence as "<address>/<sequence>"
+	query := fmt.Sprintf("tm.event='Tx' AND tx.acc_seq CONTAINS '%s/'", w.Bech32Addr)
+	events, err := client.Subscribe(context.Background(), websocketSubscriber, query)
+	if err != nil {
+		return err
+	}
+	defer client.UnsubscribeAll(context.Background(), websocketSubscriber)
+
+	w.logger().Info("subscribed to tx events", F("query", query))
+	for {
+		select {
+		case <-c.stop:
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return fmt.Errorf("tx events subscription closed")
+			}
+			data, ok := event.Data.(cmttypes.EventDataTx)
+			if !ok {
+				continue
+			}
+			w.ResolveTx(txResponseFromEvent(data))
+		}
+	}
+}
+
+// txResponseFromEvent returns the tx response of the tx committed in a Tx event
+func txResponseFromEvent(data cmttypes.EventDataTx) *types.TxResponse {
+	tx := cmttypes.Tx(data.Tx)
+	return types.NewResponseResultTx(&coretypes.ResultTx{
+		Hash:     tx.Hash(),
+		Height:   data.Height,
+		Index:    data.Index,
+		TxResult: data.Result,
+		Tx:       tx,
+	}, nil, "")
+}